@@ -0,0 +1,207 @@
+package csvutils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	badger "github.com/dgraph-io/badger/v3"
+	"github.com/eliran89c/s3pitr/pkg/s3scanner"
+)
+
+// ArchiveRestoreAPI is the minimal S3 client surface needed to initiate Glacier / Deep
+// Archive restores while walking a scanned bucket.
+type ArchiveRestoreAPI interface {
+	RestoreObject(ctx context.Context, params *s3.RestoreObjectInput, optFns ...func(*s3.Options)) (*s3.RestoreObjectOutput, error)
+}
+
+// ArchiveRestoreRecord is a single archived version awaiting a RestoreObject call.
+type ArchiveRestoreRecord struct {
+	Key      string
+	Metadata *s3scanner.S3ObjectMetadata
+}
+
+// ArchiveRestoreResult is the outcome of issuing a RestoreObject call for an
+// ArchiveRestoreRecord. Err is non-nil if the call failed; Record.Metadata.RestoreStatus
+// is only updated when Err is nil.
+type ArchiveRestoreResult struct {
+	ArchiveRestoreRecord
+	Err error
+}
+
+// archiveInitiator issues RestoreObject calls with bounded concurrency, mirroring the
+// worker-pool pattern used by s3scanner.Scanner, restore.Executor, and Verifier.
+type archiveInitiator struct {
+	client     ArchiveRestoreAPI
+	ctx        context.Context
+	workerPool chan struct{}
+	logger     *log.Logger
+	bucketName string
+	tier       types.Tier
+	days       int32
+}
+
+func newArchiveInitiator(client ArchiveRestoreAPI, ctx context.Context, maxConcurrentRestores int, bucketName string, tier types.Tier, days int32) (*archiveInitiator, error) {
+	if maxConcurrentRestores <= 0 {
+		return nil, fmt.Errorf("maxConcurrentRestores must be greater than 0")
+	}
+
+	workerPool := make(chan struct{}, maxConcurrentRestores)
+	for i := 0; i < maxConcurrentRestores; i++ {
+		workerPool <- struct{}{}
+	}
+
+	return &archiveInitiator{
+		client:     client,
+		ctx:        ctx,
+		workerPool: workerPool,
+		logger:     log.New(io.Discard, "", 0),
+		bucketName: bucketName,
+		tier:       tier,
+		days:       days,
+	}, nil
+}
+
+func (a *archiveInitiator) acquireWorker() {
+	<-a.workerPool
+}
+
+func (a *archiveInitiator) releaseWorker() {
+	a.workerPool <- struct{}{}
+}
+
+// initiate consumes ArchiveRestoreRecords from recordsCh, issuing a RestoreObject call
+// for each one concurrently bounded by the initiator's worker pool, and streams an
+// ArchiveRestoreResult for every record on the returned channel. A single record's
+// RestoreObject call failing (e.g. a transient throttle) only fails that record; it
+// never stops the rest of the batch from being restored.
+func (a *archiveInitiator) initiate(recordsCh <-chan ArchiveRestoreRecord) <-chan ArchiveRestoreResult {
+	resultsCh := make(chan ArchiveRestoreResult)
+
+	go func() {
+		var wg sync.WaitGroup
+
+		for record := range recordsCh {
+			a.acquireWorker()
+			wg.Add(1)
+
+			go func(r ArchiveRestoreRecord) {
+				defer a.releaseWorker()
+				defer wg.Done()
+
+				resultsCh <- a.initiateOne(r)
+			}(record)
+		}
+
+		wg.Wait()
+		close(resultsCh)
+	}()
+
+	return resultsCh
+}
+
+func (a *archiveInitiator) initiateOne(r ArchiveRestoreRecord) ArchiveRestoreResult {
+	_, err := a.client.RestoreObject(a.ctx, &s3.RestoreObjectInput{
+		Bucket:    aws.String(a.bucketName),
+		Key:       aws.String(r.Key),
+		VersionId: r.Metadata.VersionId,
+		RestoreRequest: &types.RestoreRequest{
+			Days: aws.Int32(a.days),
+			GlacierJobParameters: &types.GlacierJobParameters{
+				Tier: a.tier,
+			},
+		},
+	})
+	if err != nil {
+		a.logger.Printf("Failed to initiate restore for %s (version %s): %v\n", r.Key, *r.Metadata.VersionId, err)
+		return ArchiveRestoreResult{ArchiveRestoreRecord: r, Err: err}
+	}
+
+	status := `ongoing-request="true"`
+	r.Metadata.RestoreStatus = &status
+	return ArchiveRestoreResult{ArchiveRestoreRecord: r}
+}
+
+// InitiateArchiveRestores scans the objects namespaced under bucketName in db (see
+// NamespaceKey) for versions in an archived storage class that have not already had a
+// restore initiated, issues a RestoreObject call for each one with the given tier and
+// retention period (bounded by maxConcurrentRestores concurrent calls), and records the
+// resulting RestoreStatus back into db so the restore subcommand knows to wait for the
+// thaw before copying. It returns the number of RestoreObject calls that succeeded.
+//
+// A record whose RestoreObject call fails is skipped rather than aborting the scan: every other record's RestoreStatus, including ones already written to db, is
+// unaffected.
+func InitiateArchiveRestores(ctx context.Context, db *badger.DB, client ArchiveRestoreAPI, bucketName string, tier types.Tier, days int32, maxConcurrentRestores int) (int, error) {
+	initiator, err := newArchiveInitiator(client, ctx, maxConcurrentRestores, bucketName, tier, days)
+	if err != nil {
+		return 0, err
+	}
+
+	keyPrefix := BucketKeyPrefix(bucketName)
+	var candidates []ArchiveRestoreRecord
+
+	err = db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = keyPrefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(keyPrefix); it.ValidForPrefix(keyPrefix); it.Next() {
+			item := it.Item()
+			var objMetadata *s3scanner.S3ObjectMetadata
+			objKey := strings.TrimPrefix(string(item.Key()), string(keyPrefix))
+
+			if err := item.Value(func(val []byte) error {
+				return json.Unmarshal(val, &objMetadata)
+			}); err != nil {
+				return err
+			}
+
+			if objMetadata.IsArchived() && objMetadata.RestoreStatus == nil {
+				candidates = append(candidates, ArchiveRestoreRecord{Key: objKey, Metadata: objMetadata})
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan for archived versions: %v", err)
+	}
+
+	recordsCh := make(chan ArchiveRestoreRecord)
+	go func() {
+		defer close(recordsCh)
+		for _, c := range candidates {
+			recordsCh <- c
+		}
+	}()
+
+	var restored []ArchiveRestoreRecord
+	for result := range initiator.initiate(recordsCh) {
+		if result.Err == nil {
+			restored = append(restored, result.ArchiveRestoreRecord)
+		}
+	}
+
+	err = db.Update(func(txn *badger.Txn) error {
+		for _, r := range restored {
+			if err := txn.Set(NamespaceKey(bucketName, r.Key), r.Metadata.Serialize()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return len(restored), fmt.Errorf("failed to record initiated restores: %v", err)
+	}
+
+	return len(restored), nil
+}
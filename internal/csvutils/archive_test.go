@@ -0,0 +1,100 @@
+package csvutils
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	badger "github.com/dgraph-io/badger/v3"
+	"github.com/eliran89c/s3pitr/pkg/s3scanner"
+)
+
+type mockArchiveRestoreClient struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (m *mockArchiveRestoreClient) RestoreObject(ctx context.Context, params *s3.RestoreObjectInput, optFns ...func(*s3.Options)) (*s3.RestoreObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.calls == nil {
+		m.calls = map[string]int{}
+	}
+	m.calls[*params.Key]++
+
+	if *params.Key == "bad" {
+		return nil, &apiError{code: "InvalidObjectState"}
+	}
+	return &s3.RestoreObjectOutput{}, nil
+}
+
+func TestInitiateArchiveRestores(t *testing.T) {
+	db := openTestDB(t)
+
+	v1, v2, v3, v4 := "v1", "v2", "v3", "v4"
+	glacier := s3scanner.StorageClassGlacier
+	standard := "STANDARD"
+	alreadyRestoring := `ongoing-request="true"`
+
+	putObject(t, db, "test-bucket", "archived", &s3scanner.S3ObjectMetadata{VersionId: &v1, StorageClass: &glacier})
+	putObject(t, db, "test-bucket", "bad", &s3scanner.S3ObjectMetadata{VersionId: &v2, StorageClass: &glacier})
+	putObject(t, db, "test-bucket", "plain", &s3scanner.S3ObjectMetadata{VersionId: &v3, StorageClass: &standard})
+	putObject(t, db, "test-bucket", "already-restoring", &s3scanner.S3ObjectMetadata{VersionId: &v4, StorageClass: &glacier, RestoreStatus: &alreadyRestoring})
+
+	client := &mockArchiveRestoreClient{}
+	initiated, err := InitiateArchiveRestores(context.Background(), db, client, "test-bucket", types.TierStandard, 1, 2)
+	if err != nil {
+		t.Fatalf("InitiateArchiveRestores failed: %v", err)
+	}
+
+	// "bad"'s RestoreObject call fails; "plain" isn't archived; "already-restoring"
+	// already has a restore in flight. Only "archived" should succeed.
+	if initiated != 1 {
+		t.Fatalf("InitiateArchiveRestores: expected 1 successful restore, got %d", initiated)
+	}
+
+	client.mu.Lock()
+	calls := client.calls
+	client.mu.Unlock()
+	if calls["plain"] != 0 {
+		t.Errorf("InitiateArchiveRestores: expected no RestoreObject call for a non-archived version, got %d", calls["plain"])
+	}
+	if calls["already-restoring"] != 0 {
+		t.Errorf("InitiateArchiveRestores: expected no RestoreObject call for a version already restoring, got %d", calls["already-restoring"])
+	}
+	if calls["bad"] != 1 {
+		t.Errorf("InitiateArchiveRestores: expected exactly 1 (failing) RestoreObject call for 'bad', got %d", calls["bad"])
+	}
+
+	// The failure for "bad" must not have discarded the successful RestoreStatus write
+	// for "archived" — confirm it actually persisted to db.
+	var stored s3scanner.S3ObjectMetadata
+	err = db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(NamespaceKey("test-bucket", "archived"))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &stored)
+		})
+	})
+	if err != nil {
+		t.Fatalf("expected 'archived' to have a persisted RestoreStatus, got error: %v", err)
+	}
+	if stored.RestoreStatus == nil {
+		t.Errorf("expected 'archived' to have a persisted RestoreStatus, got %+v", stored)
+	}
+}
+
+func TestInitiateArchiveRestoresInvalidConcurrency(t *testing.T) {
+	db := openTestDB(t)
+	client := &mockArchiveRestoreClient{}
+
+	if _, err := InitiateArchiveRestores(context.Background(), db, client, "test-bucket", types.TierStandard, 1, 0); err == nil {
+		t.Error("InitiateArchiveRestores: expected an error for maxConcurrentRestores <= 0")
+	}
+}
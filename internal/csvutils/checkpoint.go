@@ -0,0 +1,72 @@
+package csvutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	badger "github.com/dgraph-io/badger/v3"
+	"github.com/eliran89c/s3pitr/pkg/s3scanner"
+)
+
+// checkpointKeyPrefix namespaces folder-checkpoint entries from scanned object metadata
+// and the --sync high-water mark within the same BadgerDB.
+const checkpointKeyPrefix = "\x02checkpoint\x00"
+
+// BadgerCheckpointStore persists s3scanner.FolderCheckpoint records in a BadgerDB, letting
+// --resume continue an interrupted scan instead of relisting it from scratch.
+type BadgerCheckpointStore struct {
+	DB *badger.DB
+}
+
+func checkpointKey(bucketName, prefix string) []byte {
+	return []byte(checkpointKeyPrefix + bucketName + "\x00" + prefix)
+}
+
+// LoadCheckpoints implements s3scanner.CheckpointStore.
+func (s *BadgerCheckpointStore) LoadCheckpoints(bucketName string) (map[string]s3scanner.FolderCheckpoint, error) {
+	checkpoints := make(map[string]s3scanner.FolderCheckpoint)
+	keyPrefix := []byte(checkpointKeyPrefix + bucketName + "\x00")
+
+	err := s.DB.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = keyPrefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(keyPrefix); it.ValidForPrefix(keyPrefix); it.Next() {
+			item := it.Item()
+			folderPrefix := strings.TrimPrefix(string(item.Key()), string(keyPrefix))
+
+			var checkpoint s3scanner.FolderCheckpoint
+			if err := item.Value(func(val []byte) error {
+				return json.Unmarshal(val, &checkpoint)
+			}); err != nil {
+				return err
+			}
+			checkpoints[folderPrefix] = checkpoint
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoints for %s: %v", bucketName, err)
+	}
+
+	return checkpoints, nil
+}
+
+// SaveCheckpoint implements s3scanner.CheckpointStore.
+func (s *BadgerCheckpointStore) SaveCheckpoint(bucketName, prefix string, checkpoint s3scanner.FolderCheckpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint for %s%s: %v", bucketName, prefix, err)
+	}
+
+	if err := s.DB.Update(func(txn *badger.Txn) error {
+		return txn.Set(checkpointKey(bucketName, prefix), data)
+	}); err != nil {
+		return fmt.Errorf("failed to save checkpoint for %s%s: %v", bucketName, prefix, err)
+	}
+
+	return nil
+}
@@ -1,10 +1,15 @@
 package csvutils
 
 import (
+	"crypto/md5"
 	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/url"
+	"os"
+	"strconv"
 	"strings"
 
 	badger "github.com/dgraph-io/badger/v3"
@@ -13,16 +18,154 @@ import (
 
 type ObjectFilterFunc func(key string, metadata *s3scanner.S3ObjectMetadata) bool
 
-func GenerateReport(w *csv.Writer, db *badger.DB, bucketName string, filters ...ObjectFilterFunc) error {
+// bucketKeySeparator joins a bucket name to an object key in BadgerDB, so that a single
+// run scanning multiple bucket targets can't collide on identical object keys.
+const bucketKeySeparator = "\x00"
+
+// NamespaceKey builds the BadgerDB key for an object scanned from bucket, scoping it to
+// that bucket so multi-bucket scans sharing one BadgerDB don't collide on object keys.
+func NamespaceKey(bucket, key string) []byte {
+	return []byte(bucket + bucketKeySeparator + key)
+}
+
+// BucketKeyPrefix returns the BadgerDB key prefix covering every object namespaced under
+// bucket by NamespaceKey.
+func BucketKeyPrefix(bucket string) []byte {
+	return []byte(bucket + bucketKeySeparator)
+}
+
+// ManifestFormat selects the encoding GenerateReport uses when writing rows.
+type ManifestFormat string
+
+const (
+	// ManifestFormatDefault writes a URL-escaped bucket,key,versionId,isDeleteMarker CSV.
+	ManifestFormatDefault ManifestFormat = ""
+	// ManifestFormatS3BatchCopy writes a manifest compatible with the S3PutObjectCopy job type.
+	ManifestFormatS3BatchCopy ManifestFormat = "s3-batch-copy"
+	// ManifestFormatS3BatchRestore writes a manifest compatible with the S3InitiateRestoreObject job type.
+	ManifestFormatS3BatchRestore ManifestFormat = "s3-batch-restore"
+)
+
+// IsS3BatchFormat reports whether format designates an S3 Batch Operations manifest
+// rather than the default human-readable CSV.
+func (f ManifestFormat) IsS3BatchFormat() bool {
+	return f == ManifestFormatS3BatchCopy || f == ManifestFormatS3BatchRestore
+}
+
+// ManifestSummary describes a manifest CSV that was just written, in the shape
+// required by S3 Batch Operations' CreateJob ETag/ObjectArn manifest spec.
+type ManifestSummary struct {
+	Format      ManifestFormat
+	ObjectCount int
+	ETag        string
+}
+
+// GenerateReport reads every object namespaced under bucketName in db (see NamespaceKey),
+// applies filters in order, and writes the surviving rows to out in the requested format.
+// For ManifestFormatDefault this is a URL-escaped bucket,key,versionId,isDeleteMarker CSV;
+// for the S3 Batch formats (bucket,key,versionId) the key is left unescaped (as S3 Batch Operations expects) and the
+// returned ManifestSummary's ETag and ObjectCount can be fed straight into a manifest.json
+// for CreateJob.
+//
+// If verifier is non-nil, every row surviving the filters, other than a delete marker, is
+// checked with a HeadObject before it's written: rows whose specific version no longer exists (e.g. permanently
+// deleted via lifecycle) are dropped, and for ManifestFormatDefault the row is annotated
+// with the size/ETag/StorageClass the head response returned. This closes the gap between
+// "the version was listed at scan time" and "the version is actually restorable now".
+func GenerateReport(out io.Writer, db *badger.DB, bucketName string, format ManifestFormat, verifier *Verifier, filters ...ObjectFilterFunc) (*ManifestSummary, error) {
+	hasher := md5.New()
+	w := csv.NewWriter(io.MultiWriter(out, hasher))
+	summary := &ManifestSummary{Format: format}
+	keyPrefix := BucketKeyPrefix(bucketName)
+
+	type row struct {
+		key      string
+		metadata *s3scanner.S3ObjectMetadata
+	}
+
+	writeRow := func(r row, verifyResult VerifyResult) error {
+		record := []string{bucketName, r.key, *r.metadata.VersionId}
+		if format == ManifestFormatDefault {
+			// The default report also carries IsDeleteMarker so the restore
+			// executor knows to replay a deletion instead of copying a version.
+			record = []string{bucketName, url.PathEscape(r.key), *r.metadata.VersionId, strconv.FormatBool(r.metadata.IsDeleteMarker)}
+			if verifier != nil {
+				record = append(record, strconv.FormatInt(verifyResult.Size, 10), verifyResult.ETag, verifyResult.StorageClass)
+			}
+		}
+		if err := w.Write(record); err != nil {
+			return fmt.Errorf("failed to write record: %v", err)
+		}
+		summary.ObjectCount++
+		return nil
+	}
+
+	// Without a verifier, every surviving row is written straight to out as it's read
+	// from the iterator, so a scan of hundreds of millions of versions never needs to
+	// hold more than one row in memory at a time. Verification requires a full pass
+	// first (HeadObject results are looked up by key+versionId once rows are known), so
+	// that path buffers rows instead.
+	if verifier == nil {
+		err := db.View(func(txn *badger.Txn) error {
+			opts := badger.DefaultIteratorOptions
+			opts.Prefix = keyPrefix
+			it := txn.NewIterator(opts)
+			defer it.Close()
+
+			for it.Seek(keyPrefix); it.ValidForPrefix(keyPrefix); it.Next() {
+				item := it.Item()
+				var objMetadata *s3scanner.S3ObjectMetadata
+				objKey := strings.TrimPrefix(string(item.Key()), string(keyPrefix))
+
+				err := item.Value(func(val []byte) error {
+					return json.Unmarshal(val, &objMetadata)
+				})
+				if err != nil {
+					return err
+				}
+
+				shouldWrite := true
+				for _, filter := range filters {
+					if !filter(objKey, objMetadata) {
+						shouldWrite = false
+						break
+					}
+				}
+				if !shouldWrite {
+					continue
+				}
+
+				if err := writeRow(row{key: objKey, metadata: objMetadata}, VerifyResult{}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to process items: %v", err)
+		}
+
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return nil, fmt.Errorf("failed to flush report: %v", err)
+		}
+
+		summary.ETag = hex.EncodeToString(hasher.Sum(nil))
+		return summary, nil
+	}
+
+	var rows []row
 	err := db.View(func(txn *badger.Txn) error {
 		opts := badger.DefaultIteratorOptions
+		opts.Prefix = keyPrefix
 		it := txn.NewIterator(opts)
 		defer it.Close()
 
-		for it.Rewind(); it.Valid(); it.Next() {
+		for it.Seek(keyPrefix); it.ValidForPrefix(keyPrefix); it.Next() {
 			item := it.Item()
 			var objMetadata *s3scanner.S3ObjectMetadata
-			objKey := string(item.Key())
+			objKey := strings.TrimPrefix(string(item.Key()), string(keyPrefix))
 
 			err := item.Value(func(val []byte) error {
 				return json.Unmarshal(val, &objMetadata)
@@ -40,18 +183,75 @@ func GenerateReport(w *csv.Writer, db *badger.DB, bucketName string, filters ...
 			}
 
 			if shouldWrite {
-				encodedKey := url.PathEscape(objKey)
-				record := []string{bucketName, encodedKey, *objMetadata.VersionId}
-				if err := w.Write(record); err != nil {
-					return fmt.Errorf("failed to write record: %v", err)
-				}
+				rows = append(rows, row{key: objKey, metadata: objMetadata})
 			}
 		}
 		return nil
 	})
 
 	if err != nil {
-		return fmt.Errorf("failed to process items: %v", err)
+		return nil, fmt.Errorf("failed to process items: %v", err)
+	}
+
+	verified := map[string]VerifyResult{}
+	recordsCh := make(chan VerifyRecord)
+	go func() {
+		defer close(recordsCh)
+		for _, r := range rows {
+			recordsCh <- VerifyRecord{Bucket: bucketName, Key: r.key, Metadata: r.metadata}
+		}
+	}()
+
+	for result := range verifier.Verify(recordsCh) {
+		verified[result.Key+"\x00"+*result.Metadata.VersionId] = result
+	}
+
+	for _, r := range rows {
+		verifyResult := verified[r.key+"\x00"+*r.metadata.VersionId]
+		if !verifyResult.Exists {
+			continue
+		}
+
+		if err := writeRow(r, verifyResult); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("failed to flush report: %v", err)
+	}
+
+	summary.ETag = hex.EncodeToString(hasher.Sum(nil))
+	return summary, nil
+}
+
+// WriteManifestSummary writes the manifest.json companion file describing an
+// S3 Batch Operations manifest CSV, in the shape CreateJob's Manifest.Spec expects.
+func WriteManifestSummary(path string, csvBucket, csvKey string, summary *ManifestSummary) error {
+	doc := struct {
+		Format      string `json:"format"`
+		ObjectCount int    `json:"objectCount"`
+		ETag        string `json:"eTag"`
+		Manifest    struct {
+			Bucket string `json:"bucket"`
+			Key    string `json:"key"`
+		} `json:"manifest"`
+	}{
+		Format:      string(summary.Format),
+		ObjectCount: summary.ObjectCount,
+		ETag:        summary.ETag,
+	}
+	doc.Manifest.Bucket = csvBucket
+	doc.Manifest.Key = csvKey
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest summary: %v", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest summary: %v", err)
 	}
 
 	return nil
@@ -65,29 +265,9 @@ func SkipLatest(key string, metadata *s3scanner.S3ObjectMetadata) bool {
 	return !metadata.IsLatest
 }
 
-func CreateExcludeFilter(excludePaths []string) ObjectFilterFunc {
-	if len(excludePaths) == 0 {
-		return func(key string, metadata *s3scanner.S3ObjectMetadata) bool {
-			return true
-		}
-	}
-
-	normalizedExcludes := make([]string, len(excludePaths))
-	for i, exclude := range excludePaths {
-		exclude = strings.TrimSpace(exclude)
-		exclude = strings.TrimPrefix(exclude, "/")
-		if len(exclude) > 0 && !strings.HasSuffix(exclude, "/") {
-			exclude += "/"
-		}
-		normalizedExcludes[i] = exclude
-	}
-
-	return func(key string, metadata *s3scanner.S3ObjectMetadata) bool {
-		for _, exclude := range normalizedExcludes {
-			if strings.HasPrefix(key, exclude) {
-				return false
-			}
-		}
-		return true
-	}
+// SkipArchived filters out versions stored in GLACIER or DEEP_ARCHIVE, since those
+// require a completed RestoreObject call before they can be restored.
+func SkipArchived(key string, metadata *s3scanner.S3ObjectMetadata) bool {
+	return !metadata.IsArchived()
 }
+
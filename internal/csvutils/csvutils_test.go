@@ -0,0 +1,135 @@
+package csvutils
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	smithy "github.com/aws/smithy-go"
+	badger "github.com/dgraph-io/badger/v3"
+	"github.com/eliran89c/s3pitr/pkg/s3scanner"
+)
+
+func openTestDB(t *testing.T) *badger.DB {
+	t.Helper()
+
+	opts := badger.DefaultOptions("").WithInMemory(true).WithLoggingLevel(badger.ERROR)
+	db, err := badger.Open(opts)
+	if err != nil {
+		t.Fatalf("failed to open in-memory badger db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func putObject(t *testing.T, db *badger.DB, bucket, key string, metadata *s3scanner.S3ObjectMetadata) {
+	t.Helper()
+
+	err := db.Update(func(txn *badger.Txn) error {
+		return txn.Set(NamespaceKey(bucket, key), metadata.Serialize())
+	})
+	if err != nil {
+		t.Fatalf("failed to seed %s/%s: %v", bucket, key, err)
+	}
+}
+
+func TestGenerateReportFilters(t *testing.T) {
+	db := openTestDB(t)
+
+	latest := true
+	notLatest := false
+	standard := "STANDARD"
+	v1, v2, deleted := "v1", "v2", "d1"
+
+	putObject(t, db, "test-bucket", "keep", &s3scanner.S3ObjectMetadata{VersionId: &v1, IsLatest: latest, StorageClass: &standard})
+	putObject(t, db, "test-bucket", "old-version", &s3scanner.S3ObjectMetadata{VersionId: &v2, IsLatest: notLatest, StorageClass: &standard})
+	putObject(t, db, "test-bucket", "marker", &s3scanner.S3ObjectMetadata{VersionId: &deleted, IsLatest: latest, IsDeleteMarker: true})
+
+	var buf bytes.Buffer
+	summary, err := GenerateReport(&buf, db, "test-bucket", ManifestFormatDefault, nil, SkipLatest, SkipDeleteMarkers)
+	if err != nil {
+		t.Fatalf("GenerateReport failed: %v", err)
+	}
+
+	if summary.ObjectCount != 1 {
+		t.Fatalf("GenerateReport: expected 1 surviving row, got %d (output: %q)", summary.ObjectCount, buf.String())
+	}
+	if !strings.Contains(buf.String(), "old-version") {
+		t.Errorf("GenerateReport: expected the non-latest version to survive the filters, got %q", buf.String())
+	}
+}
+
+func TestGenerateReportSkipArchived(t *testing.T) {
+	db := openTestDB(t)
+
+	v1, v2 := "v1", "v2"
+	glacier := s3scanner.StorageClassGlacier
+	standard := "STANDARD"
+
+	putObject(t, db, "test-bucket", "archived", &s3scanner.S3ObjectMetadata{VersionId: &v1, StorageClass: &glacier})
+	putObject(t, db, "test-bucket", "plain", &s3scanner.S3ObjectMetadata{VersionId: &v2, StorageClass: &standard})
+
+	var buf bytes.Buffer
+	summary, err := GenerateReport(&buf, db, "test-bucket", ManifestFormatDefault, nil, SkipArchived)
+	if err != nil {
+		t.Fatalf("GenerateReport failed: %v", err)
+	}
+
+	if summary.ObjectCount != 1 {
+		t.Fatalf("GenerateReport: expected 1 surviving row, got %d (output: %q)", summary.ObjectCount, buf.String())
+	}
+	if !strings.Contains(buf.String(), "plain") || strings.Contains(buf.String(), "archived") {
+		t.Errorf("GenerateReport: expected only the non-archived version to survive, got %q", buf.String())
+	}
+}
+
+type mockVerifyClient struct{}
+
+func (m *mockVerifyClient) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	if *params.Key == "gone" {
+		return nil, &apiError{code: "NoSuchVersion"}
+	}
+	return &s3.HeadObjectOutput{ContentLength: aws.Int64(42), StorageClass: "STANDARD"}, nil
+}
+
+func TestGenerateReportVerify(t *testing.T) {
+	db := openTestDB(t)
+
+	v1, v2 := "v1", "v2"
+	standard := "STANDARD"
+
+	putObject(t, db, "test-bucket", "present", &s3scanner.S3ObjectMetadata{VersionId: &v1, StorageClass: &standard})
+	putObject(t, db, "test-bucket", "gone", &s3scanner.S3ObjectMetadata{VersionId: &v2, StorageClass: &standard})
+
+	verifier, err := NewVerifier(&mockVerifyClient{}, context.Background(), 2)
+	if err != nil {
+		t.Fatalf("NewVerifier failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	summary, err := GenerateReport(&buf, db, "test-bucket", ManifestFormatDefault, verifier)
+	if err != nil {
+		t.Fatalf("GenerateReport failed: %v", err)
+	}
+
+	if summary.ObjectCount != 1 {
+		t.Fatalf("GenerateReport: expected the missing version to be dropped, got %d rows (output: %q)", summary.ObjectCount, buf.String())
+	}
+	if !strings.Contains(buf.String(), "present") || strings.Contains(buf.String(), "gone") {
+		t.Errorf("GenerateReport: expected only the present version to survive verification, got %q", buf.String())
+	}
+}
+
+// apiError is a minimal smithy.APIError for exercising isNotFoundError's
+// errors.As-based classification.
+type apiError struct{ code string }
+
+func (e *apiError) Error() string                 { return e.code }
+func (e *apiError) ErrorCode() string             { return e.code }
+func (e *apiError) ErrorMessage() string          { return e.code }
+func (e *apiError) ErrorFault() smithy.ErrorFault { return smithy.FaultUnknown }
@@ -0,0 +1,64 @@
+package csvutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v3"
+)
+
+// syncMarkKeyPrefix namespaces the --sync high-water mark from scanned object metadata and
+// folder checkpoints within the same BadgerDB.
+const syncMarkKeyPrefix = "\x01sync-max\x00"
+
+func syncMarkKey(bucketName string) []byte {
+	return []byte(syncMarkKeyPrefix + bucketName)
+}
+
+// ReadSyncMark returns the newest version LastModified timestamp recorded for bucketName
+// by a previous --sync run, or nil if none has been recorded yet.
+func ReadSyncMark(db *badger.DB, bucketName string) (*time.Time, error) {
+	var mark *time.Time
+
+	err := db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(syncMarkKey(bucketName))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			var t time.Time
+			if err := json.Unmarshal(val, &t); err != nil {
+				return err
+			}
+			mark = &t
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync mark for %s: %v", bucketName, err)
+	}
+
+	return mark, nil
+}
+
+// WriteSyncMark records the newest version LastModified timestamp seen for bucketName
+// during this run, so a future --sync run only ingests versions newer than it.
+func WriteSyncMark(db *badger.DB, bucketName string, mark time.Time) error {
+	data, err := json.Marshal(mark)
+	if err != nil {
+		return fmt.Errorf("failed to marshal sync mark for %s: %v", bucketName, err)
+	}
+
+	if err := db.Update(func(txn *badger.Txn) error {
+		return txn.Set(syncMarkKey(bucketName), data)
+	}); err != nil {
+		return fmt.Errorf("failed to write sync mark for %s: %v", bucketName, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package csvutils
+
+import (
+	"fmt"
+
+	badger "github.com/dgraph-io/badger/v3"
+	"github.com/eliran89c/s3pitr/internal/restore"
+)
+
+// thawKeyPrefix namespaces in-flight-restore entries from everything else kept in the
+// same BadgerDB.
+const thawKeyPrefix = "\x03thaw\x00"
+
+// BadgerThawStore persists which object versions have a Glacier/Deep Archive restore in
+// flight in a BadgerDB, letting restore.Executor resume a `restore` run without reissuing
+// RestoreObject (and resetting the clock) for a version a previous, interrupted run
+// already started restoring.
+type BadgerThawStore struct {
+	DB *badger.DB
+}
+
+func thawKey(r restore.Record) []byte {
+	return []byte(thawKeyPrefix + r.Bucket + "\x00" + r.Key + "\x00" + r.VersionId)
+}
+
+// IsThawing implements restore.ThawStore.
+func (s *BadgerThawStore) IsThawing(r restore.Record) (bool, error) {
+	found := false
+
+	err := s.DB.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(thawKey(r))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check thaw state for %s (version %s): %v", r.Key, r.VersionId, err)
+	}
+
+	return found, nil
+}
+
+// SaveThawing implements restore.ThawStore.
+func (s *BadgerThawStore) SaveThawing(r restore.Record) error {
+	if err := s.DB.Update(func(txn *badger.Txn) error {
+		return txn.Set(thawKey(r), []byte{1})
+	}); err != nil {
+		return fmt.Errorf("failed to save thaw state for %s (version %s): %v", r.Key, r.VersionId, err)
+	}
+
+	return nil
+}
+
+// ClearThawing implements restore.ThawStore.
+func (s *BadgerThawStore) ClearThawing(r restore.Record) error {
+	if err := s.DB.Update(func(txn *badger.Txn) error {
+		return txn.Delete(thawKey(r))
+	}); err != nil {
+		return fmt.Errorf("failed to clear thaw state for %s (version %s): %v", r.Key, r.VersionId, err)
+	}
+
+	return nil
+}
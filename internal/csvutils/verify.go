@@ -0,0 +1,161 @@
+package csvutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	smithy "github.com/aws/smithy-go"
+
+	"github.com/eliran89c/s3pitr/pkg/s3scanner"
+)
+
+// VerifyAPI is the minimal S3 client surface needed to confirm a scanned version is
+// still present before GenerateReport writes it to the report.
+type VerifyAPI interface {
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+}
+
+// VerifyRecord is a single report row awaiting a HeadObject confirmation.
+type VerifyRecord struct {
+	Bucket   string
+	Key      string
+	Metadata *s3scanner.S3ObjectMetadata
+}
+
+// VerifyResult is the outcome of checking a VerifyRecord against live S3. Exists is
+// false only when HeadObject reports the specific version is gone (e.g. permanently
+// deleted via lifecycle); Size, ETag, and StorageClass reflect the head response and
+// are only meaningful when Exists is true.
+type VerifyResult struct {
+	VerifyRecord
+	Exists       bool
+	Size         int64
+	ETag         string
+	StorageClass string
+}
+
+// Verifier confirms, with bounded concurrency, that report rows still exist in S3
+// before GenerateReport writes them. It mirrors the worker-pool pattern used by
+// s3scanner.Scanner and restore.Executor.
+type Verifier struct {
+	client     VerifyAPI
+	ctx        context.Context
+	workerPool chan struct{}
+	logger     *log.Logger
+}
+
+// NewVerifier creates a new Verifier with the specified context and maximum number of
+// concurrent HeadObject calls. It returns a pointer to the Verifier and an error if
+// any occurred.
+func NewVerifier(client VerifyAPI, ctx context.Context, maxConcurrentVerifications int) (*Verifier, error) {
+	if maxConcurrentVerifications <= 0 {
+		return nil, fmt.Errorf("maxConcurrentVerifications must be greater than 0")
+	}
+
+	workerPool := make(chan struct{}, maxConcurrentVerifications)
+	for i := 0; i < maxConcurrentVerifications; i++ {
+		workerPool <- struct{}{}
+	}
+
+	return &Verifier{
+		client:     client,
+		ctx:        ctx,
+		workerPool: workerPool,
+		logger:     log.New(io.Discard, "", 0),
+	}, nil
+}
+
+// SetLogger allows users to set a custom logger for the Verifier instance.
+func (v *Verifier) SetLogger(logger *log.Logger) {
+	v.logger = logger
+}
+
+func (v *Verifier) acquireWorker() {
+	<-v.workerPool
+}
+
+func (v *Verifier) releaseWorker() {
+	v.workerPool <- struct{}{}
+}
+
+// Verify consumes VerifyRecords from recordsCh, heading each one concurrently bounded
+// by the Verifier's worker pool, and streams a VerifyResult for every record on the
+// returned channel.
+func (v *Verifier) Verify(recordsCh <-chan VerifyRecord) <-chan VerifyResult {
+	resultsCh := make(chan VerifyResult)
+
+	go func() {
+		var wg sync.WaitGroup
+
+		for record := range recordsCh {
+			v.acquireWorker()
+			wg.Add(1)
+
+			go func(r VerifyRecord) {
+				defer v.releaseWorker()
+				defer wg.Done()
+
+				resultsCh <- v.verifyOne(r)
+			}(record)
+		}
+
+		wg.Wait()
+		close(resultsCh)
+	}()
+
+	return resultsCh
+}
+
+func (v *Verifier) verifyOne(r VerifyRecord) VerifyResult {
+	if r.Metadata.IsDeleteMarker {
+		// Delete markers have no object body for HeadObject to confirm; replaying the
+		// deletion doesn't depend on the marker still existing.
+		return VerifyResult{VerifyRecord: r, Exists: true}
+	}
+
+	resp, err := v.client.HeadObject(v.ctx, &s3.HeadObjectInput{
+		Bucket:    aws.String(r.Bucket),
+		Key:       aws.String(r.Key),
+		VersionId: r.Metadata.VersionId,
+	})
+	if err != nil {
+		if isNotFoundError(err) {
+			return VerifyResult{VerifyRecord: r, Exists: false}
+		}
+
+		v.logger.Printf("Failed to verify %s (version %s): %v\n", r.Key, *r.Metadata.VersionId, err)
+		// An unexpected error (throttling, network blip) shouldn't drop a version that
+		// might still be restorable, so keep it rather than treat it as a 404.
+		return VerifyResult{VerifyRecord: r, Exists: true}
+	}
+
+	result := VerifyResult{VerifyRecord: r, Exists: true, StorageClass: string(resp.StorageClass)}
+	if resp.ContentLength != nil {
+		result.Size = *resp.ContentLength
+	}
+	if resp.ETag != nil {
+		result.ETag = strings.Trim(*resp.ETag, `"`)
+	}
+
+	return result
+}
+
+// isNotFoundError reports whether err is the S3 API's "no such version" response,
+// as opposed to a transient failure that should leave the row in the report.
+func isNotFoundError(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "NotFound", "NoSuchKey", "NoSuchVersion":
+			return true
+		}
+	}
+	return false
+}
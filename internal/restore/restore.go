@@ -0,0 +1,315 @@
+// Package restore executes the version-copy-in-place operation that turns a
+// s3pitr report into an actual point-in-time restore.
+package restore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	smithy "github.com/aws/smithy-go"
+)
+
+// S3RestoreAPI is the minimal S3 client surface the Executor needs to replay
+// versions back in place. Mirrors the narrow-interface pattern used by
+// s3scanner.S3ClientAPI so a mock client can stand in for tests.
+//
+// RestoreObject and HeadObject are only exercised when a CopyObject call reports the
+// version is still archived (InvalidObjectState): the Executor initiates a restore and
+// polls HeadObject until it thaws before retrying the copy.
+type S3RestoreAPI interface {
+	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	RestoreObject(ctx context.Context, params *s3.RestoreObjectInput, optFns ...func(*s3.Options)) (*s3.RestoreObjectOutput, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+}
+
+// Record is a single object version to restore, as produced by
+// csvutils.GenerateReport or streamed directly from a scan.
+type Record struct {
+	Bucket         string
+	Key            string
+	VersionId      string
+	IsDeleteMarker bool
+}
+
+// ThawStore lets the Executor persist which Records have a Glacier/Deep Archive restore
+// in flight, so a process interrupted mid-wait doesn't reissue RestoreObject (which would
+// reset the clock) for a version a previous run already started restoring — a Bulk
+// restore can take up to 12 hours, far longer than a single run of this tool.
+type ThawStore interface {
+	// IsThawing reports whether r already has a restore in flight.
+	IsThawing(r Record) (bool, error)
+	// SaveThawing records that r has a restore in flight.
+	SaveThawing(r Record) error
+	// ClearThawing removes r once its restore has completed.
+	ClearThawing(r Record) error
+}
+
+// Result reports the outcome of restoring a single Record.
+type Result struct {
+	Record
+	Err error
+}
+
+// Executor restores object versions in place using a bounded worker pool, the
+// same pattern s3scanner.Scanner uses for concurrent folder scans.
+type Executor struct {
+	client       S3RestoreAPI
+	ctx          context.Context
+	workerPool   chan struct{}
+	logger       *log.Logger
+	maxRetries   int
+	restoreTier  types.Tier
+	restoreDays  int32
+	pollInterval time.Duration
+	thawStore    ThawStore
+}
+
+// NewExecutor creates a new Executor with the specified context and maximum number
+// of concurrent restores. It returns a pointer to the Executor and an error if any occurred.
+func NewExecutor(client S3RestoreAPI, ctx context.Context, maxConcurrentRestores int) (*Executor, error) {
+	if maxConcurrentRestores <= 0 {
+		return nil, fmt.Errorf("maxConcurrentRestores must be greater than 0")
+	}
+
+	workerPool := make(chan struct{}, maxConcurrentRestores)
+	for i := 0; i < maxConcurrentRestores; i++ {
+		workerPool <- struct{}{}
+	}
+
+	return &Executor{
+		client:       client,
+		ctx:          ctx,
+		workerPool:   workerPool,
+		logger:       log.New(io.Discard, "", 0),
+		maxRetries:   5,
+		restoreTier:  types.TierStandard,
+		restoreDays:  1,
+		pollInterval: 30 * time.Second,
+	}, nil
+}
+
+// SetLogger allows users to set a custom logger for the Executor instance.
+func (e *Executor) SetLogger(logger *log.Logger) {
+	e.logger = logger
+}
+
+// SetArchiveRestore overrides the Glacier tier and retention (days) the Executor
+// requests when a version it's asked to copy turns out to still be archived. The
+// default is Standard/1 day.
+func (e *Executor) SetArchiveRestore(tier types.Tier, days int32) {
+	e.restoreTier = tier
+	e.restoreDays = days
+}
+
+// SetPollInterval overrides how often a thawing version's restore status is
+// re-checked before the copy is retried. The default is 30 seconds; interval <= 0
+// leaves the current interval unchanged.
+func (e *Executor) SetPollInterval(interval time.Duration) {
+	if interval > 0 {
+		e.pollInterval = interval
+	}
+}
+
+// SetThawStore enables resuming a restore across runs: before issuing RestoreObject for a
+// still-archived version, the Executor checks store.IsThawing and skips straight to
+// polling if a restore is already in flight, records it via store.SaveThawing once issued,
+// and removes it via store.ClearThawing once the version has thawed.
+func (e *Executor) SetThawStore(store ThawStore) {
+	e.thawStore = store
+}
+
+func (e *Executor) acquireWorker() {
+	<-e.workerPool
+}
+
+func (e *Executor) releaseWorker() {
+	e.workerPool <- struct{}{}
+}
+
+// Restore consumes Records from recordsCh, restoring each one concurrently bounded by
+// the Executor's worker pool, and streams a Result for every Record on the returned
+// channel so callers can write a per-object success/failure report as results arrive,
+// making partial runs resumable.
+func (e *Executor) Restore(recordsCh <-chan Record) <-chan Result {
+	resultsCh := make(chan Result)
+
+	go func() {
+		var wg sync.WaitGroup
+
+		for record := range recordsCh {
+			e.acquireWorker()
+			wg.Add(1)
+
+			go func(r Record) {
+				defer e.releaseWorker()
+				defer wg.Done()
+
+				err := e.restoreOne(r)
+				if err != nil {
+					e.logger.Printf("Failed to restore %s (version %s): %v\n", r.Key, r.VersionId, err)
+				}
+				resultsCh <- Result{Record: r, Err: err}
+			}(record)
+		}
+
+		wg.Wait()
+		close(resultsCh)
+	}()
+
+	return resultsCh
+}
+
+func (e *Executor) restoreOne(r Record) error {
+	if r.IsDeleteMarker {
+		return e.withRetry(func() error {
+			_, err := e.client.DeleteObject(e.ctx, &s3.DeleteObjectInput{
+				Bucket: aws.String(r.Bucket),
+				Key:    aws.String(r.Key),
+			})
+			return err
+		})
+	}
+
+	copySource := fmt.Sprintf("%s/%s?versionId=%s", r.Bucket, url.PathEscape(r.Key), r.VersionId)
+	copyOnce := func() error {
+		_, err := e.client.CopyObject(e.ctx, &s3.CopyObjectInput{
+			Bucket:     aws.String(r.Bucket),
+			Key:        aws.String(r.Key),
+			CopySource: aws.String(copySource),
+		})
+		return err
+	}
+
+	err := e.withRetry(copyOnce)
+	if err == nil || !isArchivedError(err) {
+		return err
+	}
+
+	// The version is still in GLACIER/DEEP_ARCHIVE and hasn't been restored: initiate
+	// a restore and wait for it to thaw before retrying the copy, rather than failing
+	// the whole run with InvalidObjectState.
+	if err := e.restoreAndWait(r); err != nil {
+		return err
+	}
+
+	return e.withRetry(copyOnce)
+}
+
+// restoreAndWait issues a RestoreObject call for r (skipped if a ThawStore says one is
+// already in flight) and polls HeadObject until the restore completes, so restoreOne's
+// copy retry lands on a readable version.
+func (e *Executor) restoreAndWait(r Record) error {
+	alreadyThawing := false
+	if e.thawStore != nil {
+		var err error
+		if alreadyThawing, err = e.thawStore.IsThawing(r); err != nil {
+			e.logger.Printf("Failed to check thaw state for %s (version %s): %v\n", r.Key, r.VersionId, err)
+		}
+	}
+
+	if !alreadyThawing {
+		_, err := e.client.RestoreObject(e.ctx, &s3.RestoreObjectInput{
+			Bucket:    aws.String(r.Bucket),
+			Key:       aws.String(r.Key),
+			VersionId: aws.String(r.VersionId),
+			RestoreRequest: &types.RestoreRequest{
+				Days: aws.Int32(e.restoreDays),
+				GlacierJobParameters: &types.GlacierJobParameters{
+					Tier: e.restoreTier,
+				},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to initiate restore for %s (version %s): %v", r.Key, r.VersionId, err)
+		}
+
+		if e.thawStore != nil {
+			if err := e.thawStore.SaveThawing(r); err != nil {
+				e.logger.Printf("Failed to save thaw state for %s (version %s): %v\n", r.Key, r.VersionId, err)
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-e.ctx.Done():
+			return e.ctx.Err()
+		case <-time.After(e.pollInterval):
+		}
+
+		resp, err := e.client.HeadObject(e.ctx, &s3.HeadObjectInput{
+			Bucket:    aws.String(r.Bucket),
+			Key:       aws.String(r.Key),
+			VersionId: aws.String(r.VersionId),
+		})
+		if err != nil {
+			e.logger.Printf("Failed to check restore status for %s (version %s): %v\n", r.Key, r.VersionId, err)
+			continue
+		}
+
+		if resp.Restore != nil && strings.Contains(*resp.Restore, `ongoing-request="false"`) {
+			if e.thawStore != nil {
+				if err := e.thawStore.ClearThawing(r); err != nil {
+					e.logger.Printf("Failed to clear thaw state for %s (version %s): %v\n", r.Key, r.VersionId, err)
+				}
+			}
+			return nil
+		}
+	}
+}
+
+// isArchivedError reports whether err is S3's response to a CopyObject/GetObject
+// call against a GLACIER/DEEP_ARCHIVE version that hasn't been restored yet.
+func isArchivedError(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode() == "InvalidObjectState"
+	}
+	return false
+}
+
+// withRetry runs fn, retrying with jittered exponential backoff when the error looks
+// like a throttling response (SlowDown / 503), and returning any other error immediately.
+func (e *Executor) withRetry(fn func() error) error {
+	var err error
+
+	for attempt := 0; attempt <= e.maxRetries; attempt++ {
+		err = fn()
+		if err == nil {
+			return nil
+		}
+
+		if !isThrottlingError(err) || attempt == e.maxRetries {
+			return err
+		}
+
+		backoff := time.Duration(math.Pow(2, float64(attempt))) * 100 * time.Millisecond
+		jitter := time.Duration(rand.Int63n(int64(backoff) + 1))
+
+		select {
+		case <-time.After(backoff + jitter):
+		case <-e.ctx.Done():
+			return e.ctx.Err()
+		}
+	}
+
+	return err
+}
+
+func isThrottlingError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "slowdown") || strings.Contains(msg, "503") || strings.Contains(msg, "throttl") || strings.Contains(msg, "toomanyrequests")
+}
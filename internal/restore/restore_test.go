@@ -0,0 +1,258 @@
+package restore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	smithy "github.com/aws/smithy-go"
+)
+
+// apiError is a minimal smithy.APIError for exercising isArchivedError's
+// errors.As-based classification.
+type apiError struct {
+	code string
+}
+
+func (e *apiError) Error() string                 { return e.code }
+func (e *apiError) ErrorCode() string             { return e.code }
+func (e *apiError) ErrorMessage() string          { return e.code }
+func (e *apiError) ErrorFault() smithy.ErrorFault { return smithy.FaultUnknown }
+
+type mockRestoreClient struct {
+	mu sync.Mutex
+
+	copyCalls     int
+	deleteCalls   int
+	restoreCalls  int
+	headCalls     int
+	failCopyCount int // number of leading CopyObject calls to fail with InvalidObjectState
+	throttleCount int // number of leading CopyObject calls to fail with a throttling error
+}
+
+func (m *mockRestoreClient) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.copyCalls++
+
+	if m.copyCalls <= m.failCopyCount {
+		return nil, &apiError{code: "InvalidObjectState"}
+	}
+	if m.copyCalls <= m.failCopyCount+m.throttleCount {
+		return nil, errors.New("SlowDown: please reduce your request rate")
+	}
+	return &s3.CopyObjectOutput{}, nil
+}
+
+func (m *mockRestoreClient) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleteCalls++
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (m *mockRestoreClient) RestoreObject(ctx context.Context, params *s3.RestoreObjectInput, optFns ...func(*s3.Options)) (*s3.RestoreObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.restoreCalls++
+	return &s3.RestoreObjectOutput{}, nil
+}
+
+func (m *mockRestoreClient) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.headCalls++
+
+	// Report the restore as still thawing on the first poll and complete afterward, to
+	// exercise the poll loop rather than completing on the first check.
+	if m.headCalls < 2 {
+		restore := `ongoing-request="true"`
+		return &s3.HeadObjectOutput{Restore: &restore}, nil
+	}
+	restore := `ongoing-request="false"`
+	return &s3.HeadObjectOutput{Restore: &restore}, nil
+}
+
+func TestExecutorRestoreVersionCopy(t *testing.T) {
+	ctx := context.Background()
+	client := &mockRestoreClient{}
+
+	executor, err := NewExecutor(client, ctx, 2)
+	if err != nil {
+		t.Fatalf("NewExecutor failed: %v", err)
+	}
+
+	recordsCh := make(chan Record, 1)
+	recordsCh <- Record{Bucket: "test-bucket", Key: "object", VersionId: "v1"}
+	close(recordsCh)
+
+	var results []Result
+	for result := range executor.Restore(recordsCh) {
+		results = append(results, result)
+	}
+
+	if len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("Restore: expected 1 successful result, got %+v", results)
+	}
+	if client.copyCalls != 1 || client.deleteCalls != 0 {
+		t.Errorf("Restore: expected 1 CopyObject call and 0 DeleteObject calls, got %d and %d", client.copyCalls, client.deleteCalls)
+	}
+}
+
+func TestExecutorRestoreDeleteMarkerReplay(t *testing.T) {
+	ctx := context.Background()
+	client := &mockRestoreClient{}
+
+	executor, err := NewExecutor(client, ctx, 2)
+	if err != nil {
+		t.Fatalf("NewExecutor failed: %v", err)
+	}
+
+	recordsCh := make(chan Record, 1)
+	recordsCh <- Record{Bucket: "test-bucket", Key: "deleted", VersionId: "d1", IsDeleteMarker: true}
+	close(recordsCh)
+
+	var results []Result
+	for result := range executor.Restore(recordsCh) {
+		results = append(results, result)
+	}
+
+	if len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("Restore: expected 1 successful result, got %+v", results)
+	}
+	if client.deleteCalls != 1 || client.copyCalls != 0 {
+		t.Errorf("Restore: expected 1 DeleteObject call and 0 CopyObject calls, got %d and %d", client.deleteCalls, client.copyCalls)
+	}
+}
+
+func TestExecutorRestoreThrottlingRetry(t *testing.T) {
+	ctx := context.Background()
+	client := &mockRestoreClient{throttleCount: 2}
+
+	executor, err := NewExecutor(client, ctx, 2)
+	if err != nil {
+		t.Fatalf("NewExecutor failed: %v", err)
+	}
+
+	recordsCh := make(chan Record, 1)
+	recordsCh <- Record{Bucket: "test-bucket", Key: "object", VersionId: "v1"}
+	close(recordsCh)
+
+	start := time.Now()
+	var results []Result
+	for result := range executor.Restore(recordsCh) {
+		results = append(results, result)
+	}
+	elapsed := time.Since(start)
+
+	if len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("Restore: expected the throttled copy to eventually succeed, got %+v", results)
+	}
+	if client.copyCalls != 3 {
+		t.Errorf("Restore: expected 2 throttled attempts plus 1 success (3 total), got %d", client.copyCalls)
+	}
+	if elapsed <= 0 {
+		t.Errorf("Restore: expected backoff between retries to take non-zero time")
+	}
+}
+
+// mapThawStore is an in-memory restore.ThawStore for exercising SetThawStore without a
+// real BadgerDB.
+type mapThawStore struct {
+	mu      sync.Mutex
+	thawing map[string]bool
+}
+
+func thawStoreKey(r Record) string {
+	return r.Bucket + "/" + r.Key + "/" + r.VersionId
+}
+
+func (s *mapThawStore) IsThawing(r Record) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.thawing[thawStoreKey(r)], nil
+}
+
+func (s *mapThawStore) SaveThawing(r Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.thawing == nil {
+		s.thawing = map[string]bool{}
+	}
+	s.thawing[thawStoreKey(r)] = true
+	return nil
+}
+
+func (s *mapThawStore) ClearThawing(r Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.thawing, thawStoreKey(r))
+	return nil
+}
+
+func TestExecutorRestoreArchivedVersionSkipsReissueWhenAlreadyThawing(t *testing.T) {
+	ctx := context.Background()
+	client := &mockRestoreClient{failCopyCount: 1}
+
+	executor, err := NewExecutor(client, ctx, 2)
+	if err != nil {
+		t.Fatalf("NewExecutor failed: %v", err)
+	}
+	executor.SetPollInterval(time.Millisecond)
+
+	record := Record{Bucket: "test-bucket", Key: "archived", VersionId: "v1"}
+	store := &mapThawStore{thawing: map[string]bool{thawStoreKey(record): true}}
+	executor.SetThawStore(store)
+
+	recordsCh := make(chan Record, 1)
+	recordsCh <- record
+	close(recordsCh)
+
+	var results []Result
+	for result := range executor.Restore(recordsCh) {
+		results = append(results, result)
+	}
+
+	if len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("Restore: expected the already-thawing version to be polled and copied, got %+v", results)
+	}
+	if client.restoreCalls != 0 {
+		t.Errorf("Restore: expected no RestoreObject call for a version already marked thawing, got %d", client.restoreCalls)
+	}
+	if thawing, _ := store.IsThawing(record); thawing {
+		t.Errorf("Restore: expected the thaw state to be cleared once the restore completed")
+	}
+}
+
+func TestExecutorRestoreArchivedVersion(t *testing.T) {
+	ctx := context.Background()
+	client := &mockRestoreClient{failCopyCount: 1}
+
+	executor, err := NewExecutor(client, ctx, 2)
+	if err != nil {
+		t.Fatalf("NewExecutor failed: %v", err)
+	}
+	executor.SetPollInterval(time.Millisecond)
+
+	recordsCh := make(chan Record, 1)
+	recordsCh <- Record{Bucket: "test-bucket", Key: "archived", VersionId: "v1"}
+	close(recordsCh)
+
+	var results []Result
+	for result := range executor.Restore(recordsCh) {
+		results = append(results, result)
+	}
+
+	if len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("Restore: expected the archived version to be restored and copied, got %+v", results)
+	}
+	if client.restoreCalls != 1 {
+		t.Errorf("Restore: expected exactly 1 RestoreObject call, got %d", client.restoreCalls)
+	}
+	if client.copyCalls != 2 {
+		t.Errorf("Restore: expected the copy to be retried once after the restore completed, got %d calls", client.copyCalls)
+	}
+}
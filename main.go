@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/csv"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -15,6 +14,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/eliran89c/s3pitr/internal/csvutils"
 	"github.com/eliran89c/s3pitr/pkg/s3scanner"
@@ -27,36 +27,115 @@ const (
 	localDBName = ".s3pitr"
 )
 
+// stringSliceFlag collects repeated occurrences of a flag (e.g. --exclude a --exclude b)
+// into a slice, for flags.Var.
+type stringSliceFlag []string
+
+func (s *stringSliceFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringSliceFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// PathList is a flag.Value that accumulates comma-separated folder prefixes across
+// repeated flag occurrences, normalizing each to a trailing-slash form (e.g. "/logs" and
+// "logs/" both become "logs/"). A bare "/" anywhere in the list means "the whole bucket"
+// and takes over the entire list: once set to root, further Set calls are no-ops.
+type PathList []string
+
+func (p *PathList) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *PathList) Set(value string) error {
+	if p.isRoot() {
+		return nil
+	}
+
+	var collected []string
+	for _, raw := range strings.Split(value, ",") {
+		item := strings.TrimSpace(raw)
+		if item == "" {
+			continue
+		}
+
+		trimmed := strings.Trim(item, "/")
+		if trimmed == "" {
+			// item was made up entirely of slashes, e.g. "/" or "//": scan everything.
+			*p = PathList{"/"}
+			return nil
+		}
+
+		collected = append(collected, trimmed+"/")
+	}
+
+	*p = append(*p, collected...)
+	return nil
+}
+
+func (p *PathList) isRoot() bool {
+	return len(*p) == 1 && (*p)[0] == "/"
+}
+
+// scanTarget pairs a bucket with a single prefix to scan within it.
+type scanTarget struct {
+	Bucket string `json:"bucket"`
+	Prefix string `json:"prefix"`
+}
+
 var (
-	startTime          = time.Now()
-	targetRestoreTime  time.Time
-	bucketName         string
-	reportName         string
-	prefix             string
-	maxConcurrentScans int
-	reportFilters      []csvutils.ObjectFilterFunc
-	profile            string
-	region             string
-	roleArn            string
+	startTime                  = time.Now()
+	targetRestoreTime          time.Time
+	bucketNames                stringSliceFlag
+	reportName                 string
+	prefixes                   PathList
+	targetsFile                string
+	maxConcurrentScans         int
+	reportFilters              []csvutils.ObjectFilterFunc
+	profile                    string
+	region                     string
+	roleArn                    string
+	manifestFormat             csvutils.ManifestFormat
+	uploadManifestURI          string
+	skipArchived               bool
+	restoreArchived            bool
+	archiveRestoreTier         types.Tier
+	archiveRestoreDays         int
+	maxConcurrentArchiveInits  int
+	excludePaths               stringSliceFlag
+	includePaths               stringSliceFlag
+	dbPath                     string
+	keepDB                     bool
+	resumeScan                 bool
+	syncMode                   bool
+	checkpointInterval         int
+	verifyReport               bool
+	maxConcurrentVerifications int
 
 	version = "dev"
 	arch    = "dev"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "restore" {
+		if err := runRestore(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	err := parseFlags()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	csvFile, err := os.Create(reportName)
+	targets, err := resolveTargets()
 	if err != nil {
-		log.Fatal("Failed to create CSV file: ", err)
+		log.Fatal(err)
 	}
-	defer csvFile.Close()
-
-	writer := csv.NewWriter(csvFile)
-	defer writer.Flush()
 
 	ctx := context.Background()
 
@@ -71,10 +150,17 @@ func main() {
 	if err != nil {
 		log.Fatal("Failed to load s3scanner: ", err)
 	}
+	// Delete markers are always recorded in the DB; --include-delete-markers only
+	// controls whether they survive into the report.
+	scanner.SetIncludeDeleted(true)
 
-	opts := badger.DefaultOptions(localDBName)
+	opts := badger.DefaultOptions(dbPath)
 	opts.Logger = nil //disable badger logger
-	defer os.RemoveAll(localDBName)
+
+	// --resume and --sync both depend on the DB surviving past this run, same as --keep-db.
+	if !keepDB && !resumeScan && !syncMode {
+		defer os.RemoveAll(dbPath)
+	}
 
 	db, err := badger.Open(opts)
 	if err != nil {
@@ -82,105 +168,333 @@ func main() {
 	}
 	defer db.Close()
 
-	spinner := spinner.New(spinner.CharSets[32], 100*time.Millisecond)
-	if prefix == "" {
-		spinner.Prefix = fmt.Sprintf("Scanning bucket: %v ", bucketName)
-	} else {
-		spinner.Prefix = fmt.Sprintf("Scanning bucket: %v with prefix: %v", bucketName, prefix)
+	if resumeScan {
+		scanner.SetCheckpointStore(&csvutils.BadgerCheckpointStore{DB: db}, checkpointInterval)
 	}
-	spinner.Start()
 
-	// Scan S3 bucket and store objects in BadgerDB
-	scanResult, err := scanner.Scan(bucketName, prefix, func(obj *s3scanner.S3Object) error {
-		dbObject := s3scanner.S3ObjectMetadata{}
-		keyBytes := []byte(*obj.Key)
+	spin := spinner.New(spinner.CharSets[32], 100*time.Millisecond)
+	spin.Start()
+	defer spin.Stop()
+
+	// A bucketSelector lets --exclude name an entire target (bucket+"/") wholesale in a
+	// multi-bucket run, without affecting the per-bucket folder/object exclusions below.
+	var bucketSelector *s3scanner.ExclusionMatcher
+	if len(bucketNames) > 1 && len(excludePaths) > 0 {
+		var bucketRootPrefixes []string
+		for _, b := range bucketNames {
+			bucketRootPrefixes = append(bucketRootPrefixes, b+"/")
+		}
+		bucketSelector = s3scanner.NewExclusionMatcher(excludePaths, bucketRootPrefixes)
+	}
 
-		// skip files created after targetTime
-		if obj.Metadata.LastModified.After(targetRestoreTime) {
-			return nil
+	var rootPrefixes []string
+	for _, target := range targets {
+		rootPrefixes = append(rootPrefixes, target.Prefix)
+	}
+
+	var exclusionMatcher *s3scanner.ExclusionMatcher
+	if len(excludePaths) > 0 || len(includePaths) > 0 {
+		exclusionMatcher = s3scanner.NewExclusionMatcher(excludePaths, rootPrefixes)
+		if err := exclusionMatcher.AddIncludes(includePaths); err != nil {
+			log.Fatal("Invalid --include pattern: ", err)
 		}
+	}
 
-		err = db.Update(func(txn *badger.Txn) error {
-			item, err := txn.Get(keyBytes)
-			if err != nil {
-				if err == badger.ErrKeyNotFound {
-					// If not exists in the DB, store the current object and continue
-					return txn.Set(keyBytes, obj.Metadata.Serialize())
+	totalStats := new(s3scanner.BucketStatistics)
+	bucketStats := map[string]*s3scanner.BucketStatistics{}
+	var scannedBuckets []string
+	syncSinceByBucket := map[string]*time.Time{}
+	newestSeenByBucket := map[string]*time.Time{}
+
+	for _, target := range targets {
+		if bucketSelector != nil && bucketSelector.ShouldSkipBucket(target.Bucket+"/") {
+			continue
+		}
+
+		if target.Prefix == "" {
+			spin.Prefix = fmt.Sprintf("Scanning bucket: %v ", target.Bucket)
+		} else {
+			spin.Prefix = fmt.Sprintf("Scanning bucket: %v with prefix: %v ", target.Bucket, target.Prefix)
+		}
+
+		var syncSince *time.Time
+		if syncMode {
+			since, ok := syncSinceByBucket[target.Bucket]
+			if !ok {
+				since, err = csvutils.ReadSyncMark(db, target.Bucket)
+				if err != nil {
+					log.Fatal(err)
 				}
-				return err
+				syncSinceByBucket[target.Bucket] = since
 			}
+			syncSince = since
+		}
 
-			err = item.Value(func(val []byte) error {
-				return json.Unmarshal(val, &dbObject)
-			})
-			if err != nil {
-				return err
-			}
+		bucket := target.Bucket
+		scanFn := func(obj *s3scanner.S3Object) error {
+			dbObject := s3scanner.S3ObjectMetadata{}
+			keyBytes := csvutils.NamespaceKey(bucket, *obj.Key)
 
-			// Store the newer version
-			if obj.Metadata.LastModified.After(*dbObject.LastModified) {
-				return txn.Set(keyBytes, obj.Metadata.Serialize())
+			// In --sync mode, a version already captured by a previous --sync run doesn't
+			// need to be re-ingested.
+			if syncSince != nil && !obj.Metadata.LastModified.After(*syncSince) {
+				return nil
 			}
 
-			if obj.Metadata.LastModified.Equal(*dbObject.LastModified) && obj.Metadata.IsLatest {
-				// If the last modified time is equal and the current object is marked as latest,
-				// we update the existing object to be the latest version.
-				return txn.Set(keyBytes, obj.Metadata.Serialize())
+			if syncMode {
+				if newest := newestSeenByBucket[bucket]; newest == nil || obj.Metadata.LastModified.After(*newest) {
+					seen := *obj.Metadata.LastModified
+					newestSeenByBucket[bucket] = &seen
+				}
 			}
 
-			if obj.Metadata.LastModified.Equal(*dbObject.LastModified) && !obj.Metadata.IsLatest && !dbObject.IsLatest {
-				// If both objects have the same last modified time and neither is marked as latest,
-				// use VersionId lexicographic comparison as a consistent tiebreaker.
-				if *obj.Metadata.VersionId > *dbObject.VersionId {
+			err = db.Update(func(txn *badger.Txn) error {
+				item, err := txn.Get(keyBytes)
+				if err != nil {
+					if err == badger.ErrKeyNotFound {
+						// If not exists in the DB, store the current object and continue
+						return txn.Set(keyBytes, obj.Metadata.Serialize())
+					}
+					return err
+				}
+
+				err = item.Value(func(val []byte) error {
+					return json.Unmarshal(val, &dbObject)
+				})
+				if err != nil {
+					return err
+				}
+
+				// Store the winner between the two versions seen so far for this key (this can
+				// happen when overlapping --prefix targets scan the same key more than once).
+				if s3scanner.IsNewerVersion(&obj.Metadata, &dbObject) {
 					return txn.Set(keyBytes, obj.Metadata.Serialize())
 				}
+
+				return nil
+			})
+
+			if err != nil {
+				return fmt.Errorf("error handling key %s: %v", *obj.Key, err)
 			}
 
 			return nil
-		})
+		}
+
+		// Scan S3 bucket and store objects in BadgerDB. ScanAt reduces each key's versions
+		// down to the one current as of targetRestoreTime (see s3scanner.IsNewerVersion),
+		// so scanFn only has to merge winners across overlapping --prefix targets.
+		var scanResult *s3scanner.BucketStatistics
+		if exclusionMatcher != nil {
+			scanResult, err = scanner.ScanAtWithExclusions(target.Bucket, target.Prefix, exclusionMatcher, targetRestoreTime, scanFn)
+		} else {
+			scanResult, err = scanner.ScanAt(target.Bucket, target.Prefix, targetRestoreTime, scanFn)
+		}
 
 		if err != nil {
-			return fmt.Errorf("error handling key %s: %v", *obj.Key, err)
+			log.Fatal(err)
 		}
 
-		return nil
-	})
+		if bucketStats[target.Bucket] == nil {
+			bucketStats[target.Bucket] = new(s3scanner.BucketStatistics)
+			scannedBuckets = append(scannedBuckets, target.Bucket)
+		}
+		bucketStats[target.Bucket].Pages += scanResult.Pages
+		bucketStats[target.Bucket].Objects += scanResult.Objects
+		totalStats.Pages += scanResult.Pages
+		totalStats.Objects += scanResult.Objects
+	}
 
-	if err != nil {
-		log.Fatal(err)
+	if syncMode {
+		for _, bucket := range scannedBuckets {
+			if newest := newestSeenByBucket[bucket]; newest != nil {
+				if err := csvutils.WriteSyncMark(db, bucket, *newest); err != nil {
+					log.Fatal(err)
+				}
+			}
+		}
 	}
 
-	spinner.Prefix = fmt.Sprintln("Generating reports")
-	if err = csvutils.GenerateReport(writer, db, bucketName, reportFilters...); err != nil {
-		log.Fatal("Error creating CSV report: ", err)
+	var verifier *csvutils.Verifier
+	if verifyReport {
+		verifier, err = csvutils.NewVerifier(client, ctx, maxConcurrentVerifications)
+		if err != nil {
+			log.Fatal("Failed to init verifier: ", err)
+		}
+	}
+
+	for _, bucket := range scannedBuckets {
+		if restoreArchived {
+			spin.Prefix = fmt.Sprintf("Initiating restores for archived versions in %v ", bucket)
+			initiated, err := csvutils.InitiateArchiveRestores(ctx, db, client, bucket, archiveRestoreTier, int32(archiveRestoreDays), maxConcurrentArchiveInits)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println("Archive restores initiated for", bucket, ":", initiated)
+		}
+
+		bucketReportName := reportName
+		if len(scannedBuckets) > 1 {
+			bucketReportName = perBucketReportName(reportName, bucket)
+		}
+
+		csvFile, err := os.Create(bucketReportName)
+		if err != nil {
+			log.Fatal("Failed to create CSV file: ", err)
+		}
+
+		spin.Prefix = fmt.Sprintf("Generating report for %v ", bucket)
+		summary, err := csvutils.GenerateReport(csvFile, db, bucket, manifestFormat, verifier, reportFilters...)
+		csvFile.Close()
+		if err != nil {
+			log.Fatal("Error creating CSV report: ", err)
+		}
+
+		if manifestFormat.IsS3BatchFormat() {
+			manifestPath := strings.TrimSuffix(bucketReportName, ".csv") + ".manifest.json"
+			manifestBucket, manifestKey := "", bucketReportName
+			if uploadManifestURI != "" {
+				manifestBucket, manifestKey, err = parseS3URI(uploadManifestURI)
+				if err != nil {
+					log.Fatal("Invalid --upload-manifest destination: ", err)
+				}
+				if len(scannedBuckets) > 1 {
+					manifestKey = perBucketReportName(manifestKey, bucket)
+				}
+			}
+
+			if err := csvutils.WriteManifestSummary(manifestPath, manifestBucket, manifestKey, summary); err != nil {
+				log.Fatal("Error writing manifest summary: ", err)
+			}
+
+			if uploadManifestURI != "" {
+				if err := uploadManifestFiles(ctx, client, manifestBucket, manifestKey, bucketReportName, manifestPath); err != nil {
+					log.Fatal("Error uploading manifest: ", err)
+				}
+			}
+		}
 	}
 
-	spinner.Stop()
+	spin.Stop()
 
 	// Print scan results
 	fmt.Println("---Statistics---")
-	fmt.Println("Number of Pages: ", scanResult.Pages)
-	fmt.Println("Number of Objects: ", scanResult.Objects)
-	fmt.Printf("Scanning Cost: %0.5f$\n", scanResult.Cost())
+	if len(scannedBuckets) > 1 {
+		for _, bucket := range scannedBuckets {
+			stats := bucketStats[bucket]
+			fmt.Printf("[%s] Pages: %d, Objects: %d\n", bucket, stats.Pages, stats.Objects)
+		}
+	}
+	fmt.Println("Number of Pages: ", totalStats.Pages)
+	fmt.Println("Number of Objects: ", totalStats.Objects)
+	fmt.Printf("Scanning Cost: %0.5f$\n", totalStats.Cost())
 	fmt.Printf("Execution Time: %s\n", time.Since(startTime).Round(time.Second))
 }
 
+// resolveTargets builds the list of bucket/prefix pairs to scan, either from a
+// --targets-file or from the cartesian product of --bucket and --prefix flags (a single
+// "" prefix if --prefix was never set).
+func resolveTargets() ([]scanTarget, error) {
+	if targetsFile != "" {
+		return loadTargetsFile(targetsFile)
+	}
+
+	if len(bucketNames) == 0 {
+		return nil, fmt.Errorf("at least one --bucket is required")
+	}
+
+	prefixValues := []string{""}
+	if len(prefixes) > 0 {
+		prefixValues = make([]string, len(prefixes))
+		for i, p := range prefixes {
+			if p == "/" {
+				p = ""
+			}
+			prefixValues[i] = p
+		}
+	}
+
+	var targets []scanTarget
+	for _, bucket := range bucketNames {
+		for _, p := range prefixValues {
+			targets = append(targets, scanTarget{Bucket: bucket, Prefix: p})
+		}
+	}
+	return targets, nil
+}
+
+// loadTargetsFile reads a JSON array of {"bucket": ..., "prefix": ...} objects, for scan
+// runs whose bucket/prefix pairs aren't a clean cartesian product of --bucket x --prefix.
+func loadTargetsFile(path string) ([]scanTarget, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read --targets-file: %v", err)
+	}
+
+	var targets []scanTarget
+	if err := json.Unmarshal(data, &targets); err != nil {
+		return nil, fmt.Errorf("failed to parse --targets-file as JSON: %v", err)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("--targets-file %q contains no targets", path)
+	}
+
+	for i, target := range targets {
+		if target.Bucket == "" {
+			return nil, fmt.Errorf("--targets-file %q has a target with no bucket", path)
+		}
+
+		if target.Prefix != "" {
+			target.Prefix = strings.TrimPrefix(target.Prefix, "/")
+			if !strings.HasSuffix(target.Prefix, "/") {
+				target.Prefix += "/"
+			}
+		}
+		targets[i] = target
+	}
+
+	return targets, nil
+}
+
+// perBucketReportName inserts bucket before a report file's ".csv" extension, so a
+// multi-bucket scan can emit one report per bucket without the files colliding.
+func perBucketReportName(reportName, bucket string) string {
+	return strings.TrimSuffix(reportName, ".csv") + "." + bucket + ".csv"
+}
+
 func parseFlags() error {
-	var timestampInput, reportNameInput string
+	var timestampInput, reportNameInput, manifestFormatInput, archiveRestoreTierInput string
 	var includeLatest, includeDeleteMarkers, printVer bool
 
 	flagsSet := flag.NewFlagSet("app", flag.ExitOnError)
 
 	flagsSet.StringVar(&timestampInput, "timestamp", "", "Restore target timestamp in the format 'YYYY-MM-DDTHH:MM:SS' (default: now)")
-	flagsSet.StringVar(&bucketName, "bucket", "", "The name of the S3 bucket to scan and restore (required)")
+	flagsSet.Var(&bucketNames, "bucket", "The name of an S3 bucket to scan and restore (repeatable, required)")
 	flagsSet.IntVar(&maxConcurrentScans, "maxConcurrentScans", 100, "Maximum number of concurrent folder scans")
-	flagsSet.StringVar(&reportNameInput, "reportName", "report.csv", "Name of the report file (default: report.csv)")
+	flagsSet.StringVar(&reportNameInput, "reportName", "report.csv", "Name of the report file (default: report.csv); one file per bucket when scanning multiple buckets")
 	flagsSet.BoolVar(&includeLatest, "include-latest", false, "Include the latest versions of the objects in the report (default: false)")
 	flagsSet.BoolVar(&includeDeleteMarkers, "include-delete-markers", false, "Include delete markers in the report (default: false)")
-	flagsSet.StringVar(&prefix, "prefix", "", "Prefix to filter objects in the report (default: all objects)")
+	flagsSet.Var(&prefixes, "prefix", "Prefix to filter objects in the report (repeatable, comma-separated; default: all objects)")
+	flagsSet.StringVar(&targetsFile, "targets-file", "", "JSON file of [{\"bucket\":..., \"prefix\":...}] targets, overriding --bucket/--prefix")
 	flagsSet.StringVar(&profile, "profile", "", "AWS profile to use for credentials")
 	flagsSet.StringVar(&region, "region", "", "AWS region to use")
 	flagsSet.StringVar(&roleArn, "role-arn", "", "AWS IAM role ARN to assume")
+	flagsSet.StringVar(&manifestFormatInput, "manifest-format", "", "Report output format: '' (default CSV), 's3-batch-copy', or 's3-batch-restore'")
+	flagsSet.StringVar(&uploadManifestURI, "upload-manifest", "", "Upload the generated report and manifest.json to this s3://bucket/key destination")
+	flagsSet.BoolVar(&skipArchived, "skip-archived", false, "Exclude GLACIER/DEEP_ARCHIVE versions from the report (default: false)")
+	flagsSet.BoolVar(&restoreArchived, "restore-archived", false, "Issue a RestoreObject call for archived versions in the target set before generating the report")
+	flagsSet.StringVar(&archiveRestoreTierInput, "restore-tier", "Standard", "Glacier restore tier for --restore-archived: Bulk, Standard, or Expedited")
+	flagsSet.IntVar(&archiveRestoreDays, "restore-days", 1, "Number of days a --restore-archived object stays restored")
+	flagsSet.IntVar(&maxConcurrentArchiveInits, "maxConcurrentArchiveInits", 50, "Maximum number of concurrent RestoreObject calls when --restore-archived is set")
+	flagsSet.Var(&excludePaths, "exclude", "Object key pattern to exclude (repeatable); supports s5cmd-style * ? ** wildcards")
+	flagsSet.Var(&includePaths, "include", "Object key glob pattern to include (repeatable); if set, an object must match at least one")
+	flagsSet.StringVar(&dbPath, "db-path", localDBName, "Path to the local BadgerDB used while scanning")
+	flagsSet.BoolVar(&keepDB, "keep-db", false, "Keep the local BadgerDB after the run instead of deleting it (default: false)")
+	flagsSet.BoolVar(&resumeScan, "resume", false, "Resume a previous scan from its checkpointed progress instead of relisting from scratch")
+	flagsSet.BoolVar(&syncMode, "sync", false, "Skip versions not newer than the last --sync run and update the high-water mark for the next one")
+	flagsSet.IntVar(&checkpointInterval, "checkpoint-interval", 100, "Number of pages between checkpoint saves when --resume is set")
+	flagsSet.BoolVar(&verifyReport, "verify", false, "HeadObject each report row before writing it, dropping versions that no longer exist (default: false)")
+	flagsSet.IntVar(&maxConcurrentVerifications, "maxConcurrentVerifications", 50, "Maximum number of concurrent HeadObject calls when --verify is set")
 	flagsSet.BoolVar(&printVer, "version", false, "Print version information")
 
 	err := flagsSet.Parse(os.Args[1:])
@@ -195,15 +509,7 @@ func parseFlags() error {
 		os.Exit(0)
 	}
 
-	if prefix != "" {
-		prefix = strings.TrimPrefix(prefix, "/")
-
-		if len(prefix) > 0 && !strings.HasSuffix(prefix, "/") {
-			prefix += "/"
-		}
-	}
-
-	if bucketName == "" {
+	if len(bucketNames) == 0 && targetsFile == "" {
 		return fmt.Errorf("bucket flags is required")
 	}
 
@@ -230,6 +536,27 @@ func parseFlags() error {
 	if !includeDeleteMarkers {
 		reportFilters = append(reportFilters, csvutils.SkipDeleteMarkers)
 	}
+	if skipArchived {
+		reportFilters = append(reportFilters, csvutils.SkipArchived)
+	}
+
+	switch types.Tier(archiveRestoreTierInput) {
+	case types.TierBulk, types.TierStandard, types.TierExpedited:
+		archiveRestoreTier = types.Tier(archiveRestoreTierInput)
+	default:
+		return fmt.Errorf("unknown --restore-tier %q", archiveRestoreTierInput)
+	}
+
+	switch csvutils.ManifestFormat(manifestFormatInput) {
+	case csvutils.ManifestFormatDefault, csvutils.ManifestFormatS3BatchCopy, csvutils.ManifestFormatS3BatchRestore:
+		manifestFormat = csvutils.ManifestFormat(manifestFormatInput)
+	default:
+		return fmt.Errorf("unknown --manifest-format %q", manifestFormatInput)
+	}
+
+	if uploadManifestURI != "" && !manifestFormat.IsS3BatchFormat() {
+		return fmt.Errorf("--upload-manifest requires --manifest-format=s3-batch-copy or s3-batch-restore")
+	}
 
 	return nil
 }
@@ -258,3 +585,56 @@ func getClientConfig(ctx context.Context) (aws.Config, error) {
 
 	return cfg, nil
 }
+
+// parseS3URI splits an "s3://bucket/key" URI into its bucket and key components.
+func parseS3URI(uri string) (bucket, key string, err error) {
+	trimmed := strings.TrimPrefix(uri, "s3://")
+	if trimmed == uri {
+		return "", "", fmt.Errorf("destination %q must start with s3://", uri)
+	}
+
+	parts := strings.SplitN(trimmed, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("destination %q must be in the form s3://bucket/key", uri)
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// uploadManifestFiles puts the generated report CSV and its manifest.json companion to
+// the given bucket/key, using the key as the report's object name and a sibling object
+// named after the key with its ".csv" suffix replaced by ".manifest.json" for the summary,
+// so CreateJob can be pointed at the manifest
+// directly without any manual editing.
+func uploadManifestFiles(ctx context.Context, client *s3.Client, bucket, key, reportPath, manifestPath string) error {
+	reportFile, err := os.Open(reportPath)
+	if err != nil {
+		return fmt.Errorf("failed to open report for upload: %v", err)
+	}
+	defer reportFile.Close()
+
+	if _, err := client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   reportFile,
+	}); err != nil {
+		return fmt.Errorf("failed to upload report to s3://%s/%s: %v", bucket, key, err)
+	}
+
+	manifestFile, err := os.Open(manifestPath)
+	if err != nil {
+		return fmt.Errorf("failed to open manifest summary for upload: %v", err)
+	}
+	defer manifestFile.Close()
+
+	manifestKey := strings.TrimSuffix(key, ".csv") + ".manifest.json"
+	if _, err := client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(manifestKey),
+		Body:   manifestFile,
+	}); err != nil {
+		return fmt.Errorf("failed to upload manifest summary to s3://%s/%s: %v", bucket, manifestKey, err)
+	}
+
+	return nil
+}
@@ -17,21 +17,44 @@ type BucketStatistics struct {
 
 type bucket struct {
 	name             string
-	root             string
 	folders          chan *bucketFolder
 	exclusionMatcher *ExclusionMatcher
+	checkpoints      map[string]FolderCheckpoint
+
+	// pendingRootFolders counts the root-level folders (the single bucket root, or one
+	// per prefix shard when ScanOptions enables sharding) that haven't finished their
+	// own listing yet. folders is closed once it reaches zero, since every other
+	// folder is discovered as a CommonPrefix of one of these and by then has already
+	// been queued.
+	pendingRootFolders int32
 }
 
 type bucketFolder struct {
-	prefix    string
-	delimiter string
+	prefix          string
+	delimiter       string
+	keyMarker       *string
+	versionIdMarker *string
+	// isRootFolder marks a folder seeded up front (the bucket root, or a shard root)
+	// rather than one discovered via a CommonPrefix during the scan.
+	isRootFolder bool
 }
 
 func (b *bucket) addFolder(prefix string) {
 	if b.exclusionMatcher != nil && b.exclusionMatcher.ShouldSkipRootFolder(prefix) {
 		return
 	}
-	b.folders <- &bucketFolder{prefix: prefix}
+
+	folder := &bucketFolder{prefix: prefix}
+	if checkpoint, ok := b.checkpoints[prefix]; ok {
+		if checkpoint.Done {
+			// Already fully listed in a previous run.
+			return
+		}
+		folder.keyMarker = checkpoint.KeyMarker
+		folder.versionIdMarker = checkpoint.VersionIdMarker
+	}
+
+	b.folders <- folder
 }
 
 func (b *bucket) closeFolders() {
@@ -39,7 +62,13 @@ func (b *bucket) closeFolders() {
 }
 
 func (b *bucket) isRoot(f *bucketFolder) bool {
-	return f.prefix == b.root && f.delimiter == "/"
+	return f.isRootFolder
+}
+
+// finishRootFolder records that one of the bucket's root-level folders (see
+// pendingRootFolders) has finished listing, and reports whether it was the last one.
+func (b *bucket) finishRootFolder() bool {
+	return atomic.AddInt32(&b.pendingRootFolders, -1) == 0
 }
 
 func (stats *BucketStatistics) addPages(p int) {
@@ -56,23 +85,64 @@ func (stats *BucketStatistics) Cost() float32 {
 	return float32(stats.Pages) * listObjectPrice
 }
 
-func newBucket(name, prefix string) *bucket {
+// rootFolder builds a single root-level folder at prefix, resuming from a previously
+// checkpointed marker if one is recorded for it (the root always relists, so this only
+// skips pages it already fetched, never the folder as a whole).
+func rootFolder(prefix string, checkpoints map[string]FolderCheckpoint) *bucketFolder {
+	folder := &bucketFolder{delimiter: "/", prefix: prefix, isRootFolder: true}
+	if checkpoint, ok := checkpoints[prefix]; ok {
+		folder.keyMarker = checkpoint.KeyMarker
+		folder.versionIdMarker = checkpoint.VersionIdMarker
+	}
+	return folder
+}
+
+// seedRootFolders queues the bucket's root-level folders: a single one at prefix, or,
+// when opts enables prefix-fanout sharding, one per shard token appended to prefix. It
+// sets pendingRootFolders so the scan knows when every root-level folder (and therefore
+// every CommonPrefix they can still discover) has been queued. folders is always sized
+// (see rootFolderBuffer) to fit every root-level folder seeded here, so these sends
+// never block.
+func seedRootFolders(b *bucket, prefix string, opts ScanOptions) {
+	shards := opts.shardTokens()
+	if len(shards) == 0 {
+		b.pendingRootFolders = 1
+		b.folders <- rootFolder(prefix, b.checkpoints)
+		return
+	}
+
+	b.pendingRootFolders = int32(len(shards))
+	for _, shard := range shards {
+		b.folders <- rootFolder(prefix+shard, b.checkpoints)
+	}
+}
+
+func newBucket(name, prefix string, checkpoints map[string]FolderCheckpoint, opts ScanOptions) *bucket {
 	b := &bucket{
-		name:    name,
-		root:    prefix,
-		folders: make(chan *bucketFolder, 1),
+		name:        name,
+		folders:     make(chan *bucketFolder, rootFolderBuffer(opts)),
+		checkpoints: checkpoints,
 	}
-	b.folders <- &bucketFolder{delimiter: "/", prefix: prefix} // manually add the root folder
+	seedRootFolders(b, prefix, opts)
 	return b
 }
 
-func newBucketWithExclusions(name, prefix string, exclusionMatcher *ExclusionMatcher) *bucket {
+func newBucketWithExclusions(name, prefix string, exclusionMatcher *ExclusionMatcher, checkpoints map[string]FolderCheckpoint, opts ScanOptions) *bucket {
 	b := &bucket{
 		name:             name,
-		root:             prefix,
-		folders:          make(chan *bucketFolder, 1),
+		folders:          make(chan *bucketFolder, rootFolderBuffer(opts)),
 		exclusionMatcher: exclusionMatcher,
+		checkpoints:      checkpoints,
 	}
-	b.folders <- &bucketFolder{delimiter: "/", prefix: prefix} // manually add the root folder
+	seedRootFolders(b, prefix, opts)
 	return b
 }
+
+// rootFolderBuffer sizes the folders channel to however many root-level folders will be
+// seeded up front, so seedRootFolders's sends never block waiting on a consumer.
+func rootFolderBuffer(opts ScanOptions) int {
+	if n := len(opts.shardTokens()); n > 0 {
+		return n
+	}
+	return 1
+}
@@ -0,0 +1,20 @@
+package s3scanner
+
+// FolderCheckpoint records pagination progress for a single folder within a scan, letting
+// an interrupted run resume instead of relisting that folder from the start.
+type FolderCheckpoint struct {
+	KeyMarker       *string
+	VersionIdMarker *string
+	Done            bool
+}
+
+// CheckpointStore persists and restores FolderCheckpoints, keyed by folder prefix, so a
+// Scanner can resume a killed scan of a bucket instead of relisting it from scratch. See
+// Scanner.SetCheckpointStore.
+type CheckpointStore interface {
+	// LoadCheckpoints returns every checkpoint previously persisted for bucketName, keyed
+	// by folder prefix.
+	LoadCheckpoints(bucketName string) (map[string]FolderCheckpoint, error)
+	// SaveCheckpoint persists (or overwrites) a single folder's checkpoint.
+	SaveCheckpoint(bucketName, prefix string, checkpoint FolderCheckpoint) error
+}
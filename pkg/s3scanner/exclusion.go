@@ -1,12 +1,24 @@
 package s3scanner
 
 import (
+	"regexp"
 	"strings"
 )
 
+// ExclusionMatcher decides which scan targets, folders, and objects a scan should skip.
+// Plain prefix excludes (no wildcards) keep the original fast root-folder pruning; excludes
+// and includes containing "*"/"?" wildcards are compiled to regular expressions and
+// evaluated per-object, since they can't be reduced to a single directory prefix. An
+// exclude that exactly matches one of the configured root prefixes is classified as a
+// bucket-level exclusion, letting a multi-bucket/multi-prefix scan drop a whole target.
 type ExclusionMatcher struct {
+	bucketExclusions []string
 	rootExclusions   []string
 	objectExclusions []string
+
+	excludeGlobs []*regexp.Regexp
+	exceptGlobs  []*regexp.Regexp // patterns prefixed with "!" that veto a would-be exclude
+	includeGlobs []*regexp.Regexp
 }
 
 func NewExclusionMatcher(excludePaths []string, rootPrefixes []string) *ExclusionMatcher {
@@ -19,16 +31,52 @@ func NewExclusionMatcher(excludePaths []string, rootPrefixes []string) *Exclusio
 	return matcher
 }
 
+// AddIncludes compiles a list of s5cmd-style include glob patterns. Once at least one
+// include pattern is set, ShouldSkipObject requires an object to match one of them (in
+// addition to failing all excludes) to be kept.
+func (e *ExclusionMatcher) AddIncludes(includePaths []string) error {
+	for _, include := range includePaths {
+		re, err := CompileGlob(strings.TrimSpace(include))
+		if err != nil {
+			return err
+		}
+		e.includeGlobs = append(e.includeGlobs, re)
+	}
+	return nil
+}
+
 func (e *ExclusionMatcher) classifyExclusions(excludePaths []string, rootPrefixes []string) {
-	for _, exclude := range excludePaths {
-		if isRootLevelExclusion(exclude, rootPrefixes) {
+	for _, raw := range excludePaths {
+		exclude := strings.TrimSpace(raw)
+
+		if negated := strings.TrimPrefix(exclude, "!"); negated != exclude {
+			if re, err := CompileGlob(negated); err == nil {
+				e.exceptGlobs = append(e.exceptGlobs, re)
+			}
+			continue
+		}
+
+		if IsGlobPattern(exclude) {
+			if re, err := CompileGlob(exclude); err == nil {
+				e.excludeGlobs = append(e.excludeGlobs, re)
+			}
+			continue
+		}
+
+		switch {
+		case isBucketLevelExclusion(exclude, rootPrefixes):
+			e.bucketExclusions = append(e.bucketExclusions, exclude)
+		case isRootLevelExclusion(exclude, rootPrefixes):
 			e.rootExclusions = append(e.rootExclusions, exclude)
-		} else {
+		default:
 			e.objectExclusions = append(e.objectExclusions, exclude)
 		}
 	}
 }
 
+// ShouldSkipRootFolder reports whether a root-level folder prefix should be pruned
+// before it is ever listed. Only literal (non-wildcard) excludes participate, since a
+// wildcard exclude can't be reduced to a single directory prefix.
 func (e *ExclusionMatcher) ShouldSkipRootFolder(folderPrefix string) bool {
 	for _, rootExclude := range e.rootExclusions {
 		if strings.HasPrefix(folderPrefix, rootExclude) {
@@ -38,9 +86,73 @@ func (e *ExclusionMatcher) ShouldSkipRootFolder(folderPrefix string) bool {
 	return false
 }
 
+// ShouldSkipBucket reports whether an entire scan target should be dropped before it is
+// ever listed, because one of its root prefixes was excluded wholesale rather than a
+// folder within it. Used for multi-bucket/multi-prefix scans, where excludePaths may name
+// a whole target (e.g. "logs/" when scanning a bucket under that root prefix).
+func (e *ExclusionMatcher) ShouldSkipBucket(bucketName string) bool {
+	for _, bucketExclude := range e.bucketExclusions {
+		if bucketExclude == bucketName {
+			return true
+		}
+	}
+	return false
+}
+
+// ShouldSkipObject reports whether objectKey should be dropped from the scan. An object is
+// kept only if it fails every exclude (literal prefix or glob, unless vetoed by a
+// "!"-prefixed exception pattern) and, if any include patterns were configured, matches at
+// least one of them.
 func (e *ExclusionMatcher) ShouldSkipObject(objectKey string) bool {
+	excluded := false
+
 	for _, objExclude := range e.objectExclusions {
 		if strings.HasPrefix(objectKey, objExclude) {
+			excluded = true
+			break
+		}
+	}
+
+	if !excluded {
+		for _, re := range e.excludeGlobs {
+			if re.MatchString(objectKey) {
+				excluded = true
+				break
+			}
+		}
+	}
+
+	if excluded {
+		for _, re := range e.exceptGlobs {
+			if re.MatchString(objectKey) {
+				excluded = false
+				break
+			}
+		}
+	}
+
+	if excluded {
+		return true
+	}
+
+	if len(e.includeGlobs) == 0 {
+		return false
+	}
+
+	for _, re := range e.includeGlobs {
+		if re.MatchString(objectKey) {
+			return false
+		}
+	}
+	return true
+}
+
+// isBucketLevelExclusion reports whether exclude names an entire root prefix rather than
+// a folder within it, so that a multi-bucket/multi-prefix scan can drop the whole target
+// instead of filtering individual folders or objects inside it.
+func isBucketLevelExclusion(exclude string, rootPrefixes []string) bool {
+	for _, rootPrefix := range rootPrefixes {
+		if rootPrefix != "" && exclude == rootPrefix {
 			return true
 		}
 	}
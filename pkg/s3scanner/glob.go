@@ -0,0 +1,39 @@
+package s3scanner
+
+import (
+	"regexp"
+	"strings"
+)
+
+// IsGlobPattern reports whether a path contains s5cmd-style wildcard metacharacters.
+func IsGlobPattern(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?")
+}
+
+// CompileGlob turns an s5cmd-style glob pattern into an anchored regular expression.
+// "*" matches any run of characters within a single path segment, "?" matches a single
+// character, and "**" matches across "/" boundaries (recursive match).
+func CompileGlob(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '*':
+			if i+1 < len(runes) && runes[i+1] == '*' {
+				sb.WriteString(".*")
+				i++
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(runes[i])))
+		}
+	}
+
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
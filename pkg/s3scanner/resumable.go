@@ -0,0 +1,71 @@
+package s3scanner
+
+import "sync"
+
+// resumeCheckpointStore implements CheckpointStore by holding FolderCheckpoints in
+// memory for the duration of a single ScanResumable call, so pagination progress can be
+// handed back to the caller to persist however it likes, instead of requiring a
+// long-lived CheckpointStore to be wired in ahead of time.
+type resumeCheckpointStore struct {
+	mu          sync.Mutex
+	checkpoints map[string]FolderCheckpoint
+}
+
+func newResumeCheckpointStore(initial map[string]FolderCheckpoint) *resumeCheckpointStore {
+	checkpoints := make(map[string]FolderCheckpoint, len(initial))
+	for k, v := range initial {
+		checkpoints[k] = v
+	}
+	return &resumeCheckpointStore{checkpoints: checkpoints}
+}
+
+// LoadCheckpoints implements CheckpointStore.
+func (s *resumeCheckpointStore) LoadCheckpoints(bucketName string) (map[string]FolderCheckpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshotLocked(), nil
+}
+
+// SaveCheckpoint implements CheckpointStore.
+func (s *resumeCheckpointStore) SaveCheckpoint(bucketName, prefix string, checkpoint FolderCheckpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoints[prefix] = checkpoint
+	return nil
+}
+
+func (s *resumeCheckpointStore) snapshot() map[string]FolderCheckpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshotLocked()
+}
+
+func (s *resumeCheckpointStore) snapshotLocked() map[string]FolderCheckpoint {
+	out := make(map[string]FolderCheckpoint, len(s.checkpoints))
+	for k, v := range s.checkpoints {
+		out[k] = v
+	}
+	return out
+}
+
+// ScanResumable behaves like Scan, except pagination progress is returned to the caller
+// as a map of FolderCheckpoint keyed by folder prefix, rather than requiring a
+// CheckpointStore to be configured ahead of time via SetCheckpointStore. It's built on
+// that exact same checkpointing path (a checkpoint is saved after every page), so this
+// package has one checkpointing mechanism, not two: pass nil to start a fresh scan, or
+// the map a previous, interrupted ScanResumable call returned, to continue from where it
+// left off. fn is called at-least-once per object, so it must be idempotent.
+//
+// ScanResumable temporarily overrides the Scanner's checkpoint store for the duration of
+// the call (restoring whatever was configured before on return), so it isn't safe to run
+// concurrently with another Scan/ScanResumable call on the same Scanner.
+func (s *Scanner) ScanResumable(bucketName, prefix string, checkpoints map[string]FolderCheckpoint, fn func(o *S3Object) error) (map[string]FolderCheckpoint, *BucketStatistics, error) {
+	store := newResumeCheckpointStore(checkpoints)
+
+	prevStore, prevInterval := s.checkpoints, s.checkpointInterval
+	s.SetCheckpointStore(store, 1)
+	defer func() { s.checkpoints, s.checkpointInterval = prevStore, prevInterval }()
+
+	stats, err := s.Scan(bucketName, prefix, fn)
+	return store.snapshot(), stats, err
+}
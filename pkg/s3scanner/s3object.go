@@ -17,6 +17,37 @@ type S3ObjectMetadata struct {
 	LastModified   *time.Time
 	IsDeleteMarker bool
 	IsLatest       bool
+
+	// StorageClass is the version's storage class as reported by ListObjectVersions
+	// (e.g. "STANDARD", "GLACIER", "DEEP_ARCHIVE"). Nil for delete markers.
+	StorageClass *string
+	// RestoreStatus records the outcome of a RestoreObject call issued for an archived
+	// version (e.g. "ongoing-request=\"true\"" or "ongoing-request=\"false\""). Nil until
+	// a restore has been initiated for this version.
+	RestoreStatus *string
+}
+
+// Archived storage classes that require a RestoreObject call before a version can be
+// read back via CopyObject or GetObject. GLACIER_IR (Glacier Instant Retrieval) is
+// deliberately excluded: it's real-time-accessible, and issuing RestoreObject against it
+// is an error.
+const (
+	StorageClassGlacier     = "GLACIER"
+	StorageClassDeepArchive = "DEEP_ARCHIVE"
+)
+
+// IsArchived reports whether the version's storage class requires a completed
+// RestoreObject call before it can be copied or read.
+func (o *S3ObjectMetadata) IsArchived() bool {
+	if o.StorageClass == nil {
+		return false
+	}
+	switch *o.StorageClass {
+	case StorageClassGlacier, StorageClassDeepArchive:
+		return true
+	default:
+		return false
+	}
 }
 
 // Serialize returns the JSON byte representation of an S3ObjectMetadata instance, ignoring any marshaling errors.
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
@@ -27,6 +28,12 @@ type Scanner struct {
 	client     S3ClientAPI
 	logger     *log.Logger
 	workerPool chan struct{}
+
+	checkpoints        CheckpointStore
+	checkpointInterval int
+
+	includeDeleted bool
+	scanOptions    ScanOptions
 }
 
 // NonVersionedBucketError is an error type representing an error
@@ -55,6 +62,38 @@ func (s *Scanner) SetLogger(logger *log.Logger) {
 	s.logger = logger
 }
 
+// SetCheckpointStore enables resumable scanning: after every interval pages within a
+// folder, and whenever a folder's pagination completes, its progress is persisted to
+// store. The next Scan/ScanWithExclusions call against the same bucket skips folders
+// recorded as done and resumes partially-scanned ones from their last marker instead of
+// relisting from scratch. The bucket's root folder (the shallow, delimited listing that
+// discovers top-level prefixes) is listed again even when recorded as done, since that's
+// what finds any new top-level folders added since the last run; if its own listing was
+// interrupted, it resumes from its last checkpointed marker. interval <= 0 leaves the
+// current interval unchanged.
+func (s *Scanner) SetCheckpointStore(store CheckpointStore, interval int) {
+	s.checkpoints = store
+	if interval > 0 {
+		s.checkpointInterval = interval
+	}
+}
+
+// SetIncludeDeleted controls whether ScanAt/ScanAtWithExclusions emit a key whose
+// point-in-time winner is a delete marker. It defaults to false, since a deleted object
+// normally shouldn't appear in a point-in-time report.
+func (s *Scanner) SetIncludeDeleted(includeDeleted bool) {
+	s.includeDeleted = includeDeleted
+}
+
+// SetScanOptions configures Scan/ScanWithExclusions/ScanAt/ScanAtWithExclusions. When
+// opts.PrefixShardLength > 0, instead of seeding a single root folder for the scanned
+// prefix, they seed one root folder per shard token (see ScanOptions), each competing
+// for the same workerPool, to work around S3's per-prefix request-rate ceiling. The
+// zero value leaves sharding disabled.
+func (s *Scanner) SetScanOptions(opts ScanOptions) {
+	s.scanOptions = opts
+}
+
 // NewScanner creates a new Scanner instance with the specified context and maximum number of concurrent folder workers.
 // It returns a pointer to the Scanner and an error if any occurred.
 func NewScanner(s3Client S3ClientAPI, ctx context.Context, maxConcurrentScans int) (*Scanner, error) {
@@ -66,15 +105,68 @@ func NewScanner(s3Client S3ClientAPI, ctx context.Context, maxConcurrentScans in
 	}
 
 	return &Scanner{
-		ctx:        ctx,
-		client:     s3Client,
-		workerPool: workerPool,
-		logger:     log.New(ioutil.Discard, "", 0),
+		ctx:                ctx,
+		client:             s3Client,
+		workerPool:         workerPool,
+		logger:             log.New(ioutil.Discard, "", 0),
+		checkpointInterval: 100,
 	}, nil
 }
 
+// deleteMarkerFirst reports whether del should be emitted before ver when merging a
+// page's DeleteMarkers and Versions lists back into key, then LastModified-descending,
+// order: a lexicographically smaller key goes first, and for the same key the more
+// recent entry goes first.
+func deleteMarkerFirst(del types.DeleteMarkerEntry, ver types.ObjectVersion) bool {
+	if *del.Key != *ver.Key {
+		return *del.Key < *ver.Key
+	}
+	return !del.LastModified.Before(*ver.LastModified)
+}
+
+// emitPage merges a single ListObjectVersions page's DeleteMarkers and Versions lists
+// back into their combined key, then LastModified-descending, order (see
+// deleteMarkerFirst) and sends each entry as an S3Object on objCh.
+func emitPage(resp *s3.ListObjectVersionsOutput, objCh chan<- *S3Object) {
+	di, vi := 0, 0
+	for di < len(resp.DeleteMarkers) || vi < len(resp.Versions) {
+		if vi >= len(resp.Versions) || (di < len(resp.DeleteMarkers) && deleteMarkerFirst(resp.DeleteMarkers[di], resp.Versions[vi])) {
+			del := resp.DeleteMarkers[di]
+			objCh <- &S3Object{
+				Key: del.Key,
+				Metadata: S3ObjectMetadata{
+					VersionId:      del.VersionId,
+					LastModified:   del.LastModified,
+					IsDeleteMarker: true,
+					IsLatest:       *del.IsLatest,
+				},
+			}
+			di++
+			continue
+		}
+
+		ver := resp.Versions[vi]
+		var storageClass *string
+		if sc := string(ver.StorageClass); sc != "" {
+			storageClass = &sc
+		}
+
+		objCh <- &S3Object{
+			Key: ver.Key,
+			Metadata: S3ObjectMetadata{
+				VersionId:      ver.VersionId,
+				LastModified:   ver.LastModified,
+				IsLatest:       *ver.IsLatest,
+				IsDeleteMarker: false,
+				StorageClass:   storageClass,
+			},
+		}
+		vi++
+	}
+}
+
 func (s *Scanner) fetchFolder(b *bucket, folder *bucketFolder, objCh chan<- *S3Object) (int, error) {
-	var nextKey, nextVersion *string
+	nextKey, nextVersion := folder.keyMarker, folder.versionIdMarker
 	pageCount := 0
 
 	for {
@@ -91,47 +183,167 @@ func (s *Scanner) fetchFolder(b *bucket, folder *bucketFolder, objCh chan<- *S3O
 			return pageCount, fmt.Errorf("failed to list object versions for bucket %s with prefix %s: %v", b.name, folder.prefix, err)
 		}
 
-		for _, del := range resp.DeleteMarkers {
-			objCh <- &S3Object{
-				Key: del.Key,
-				Metadata: S3ObjectMetadata{
-					VersionId:      del.VersionId,
-					LastModified:   del.LastModified,
-					IsDeleteMarker: true,
-					IsLatest:       *del.IsLatest,
-				},
-			}
+		// A shard root's first page coming back completely empty means the shard's
+		// keyspace has nothing in it; stop instead of recursing into CommonPrefixes or
+		// paginating further, so an unpopulated shard token doesn't cost more than one
+		// request.
+		if pageCount == 1 && folder.isRootFolder && len(resp.Versions) == 0 && len(resp.DeleteMarkers) == 0 && len(resp.CommonPrefixes) == 0 {
+			break
 		}
 
-		for _, ver := range resp.Versions {
-			objCh <- &S3Object{
-				Key: ver.Key,
-				Metadata: S3ObjectMetadata{
-					VersionId:      ver.VersionId,
-					LastModified:   ver.LastModified,
-					IsLatest:       *ver.IsLatest,
-					IsDeleteMarker: false,
-				},
-			}
-		}
+		emitPage(resp, objCh)
 
 		for _, commonPrefix := range resp.CommonPrefixes {
 			b.addFolder(*commonPrefix.Prefix)
 		}
 
+		nextKey = resp.NextKeyMarker
+		nextVersion = resp.NextVersionIdMarker
+
+		if s.checkpoints != nil && pageCount%s.checkpointInterval == 0 {
+			s.saveCheckpoint(b.name, folder.prefix, nextKey, nextVersion, false)
+		}
+
 		if !*resp.IsTruncated {
 			break
 		}
+	}
 
-		nextKey = resp.NextKeyMarker
-		nextVersion = resp.NextVersionIdMarker
+	if s.checkpoints != nil {
+		s.saveCheckpoint(b.name, folder.prefix, nil, nil, true)
 	}
+
 	return pageCount, nil
 }
 
+func (s *Scanner) saveCheckpoint(bucketName, prefix string, keyMarker, versionIdMarker *string, done bool) {
+	checkpoint := FolderCheckpoint{KeyMarker: keyMarker, VersionIdMarker: versionIdMarker, Done: done}
+	if err := s.checkpoints.SaveCheckpoint(bucketName, prefix, checkpoint); err != nil {
+		s.logger.Printf("Failed to save checkpoint for prefix '%s': %v\n", prefix, err)
+	}
+}
+
+// reduceAtTarget returns the per-folder object consumer ScanAt/ScanAtWithExclusions use.
+// ListObjectVersions returns each key's versions grouped together and sorted
+// newest-first, so it only has to track the current key's best-so-far candidate instead
+// of buffering every version: it skips anything newer than target, keeps the winner
+// IsNewerVersion prefers, and decides and emits on every key transition, which keeps
+// memory O(1) per key regardless of how many versions it has.
+func (s *Scanner) reduceAtTarget(exclusionMatcher *ExclusionMatcher, target time.Time) func(objCh <-chan *S3Object, fn func(o *S3Object) error) int {
+	return func(objCh <-chan *S3Object, fn func(o *S3Object) error) int {
+		i := 0
+		var key string
+		var winner *S3Object
+
+		emit := func() {
+			if winner == nil {
+				return
+			}
+			if winner.Metadata.IsDeleteMarker && !s.includeDeleted {
+				return
+			}
+			i++
+			if err := fn(winner); err != nil {
+				s.logger.Println("Error in object processing function:", err)
+			}
+		}
+
+		for obj := range objCh {
+			if exclusionMatcher != nil && exclusionMatcher.ShouldSkipObject(*obj.Key) {
+				continue
+			}
+			if obj.Metadata.LastModified.After(target) {
+				continue
+			}
+
+			if winner == nil || *obj.Key != key {
+				emit()
+				key = *obj.Key
+				winner = obj
+				continue
+			}
+
+			if IsNewerVersion(&obj.Metadata, &winner.Metadata) {
+				winner = obj
+			}
+		}
+		emit()
+
+		return i
+	}
+}
+
+// IsNewerVersion reports whether candidate should replace current as a key's
+// point-in-time winner: a strictly newer LastModified always wins; given an equal
+// LastModified, a version marked IsLatest wins outright, and otherwise the
+// lexicographically greater VersionId is the tiebreaker. ScanAt uses this to reduce a
+// key's versions as they stream in; callers merging winners across several scans of the
+// same key (e.g. overlapping --prefix targets) can use it too instead of re-deriving the
+// same comparison.
+func IsNewerVersion(candidate, current *S3ObjectMetadata) bool {
+	if candidate.LastModified.After(*current.LastModified) {
+		return true
+	}
+	if !candidate.LastModified.Equal(*current.LastModified) {
+		return false
+	}
+	if candidate.IsLatest {
+		return true
+	}
+	if !current.IsLatest {
+		return *candidate.VersionId > *current.VersionId
+	}
+	return false
+}
+
 // Scan performs a concurrent scan of the specified S3 bucket, processing each object using the provided function.
 // It returns a pointer to a BucketStatistics instance containing the number of pages and objects processed, and an error if any occurred.
 func (s *Scanner) Scan(bucketName, prefix string, fn func(o *S3Object) error) (*BucketStatistics, error) {
+	return s.scan(bucketName, prefix, nil, s.consumeObjects(nil), fn)
+}
+
+// ScanWithExclusions behaves like Scan but applies exclusionMatcher to prune excluded
+// root folders before they are ever listed and to drop individual excluded (or
+// not-included) objects as they stream in, supporting s5cmd-style glob --include/--exclude
+// patterns that a single directory prefix can't express.
+func (s *Scanner) ScanWithExclusions(bucketName, prefix string, exclusionMatcher *ExclusionMatcher, fn func(o *S3Object) error) (*BucketStatistics, error) {
+	return s.scan(bucketName, prefix, exclusionMatcher, s.consumeObjects(exclusionMatcher), fn)
+}
+
+// ScanAt behaves like Scan, except that instead of passing through every version and
+// delete marker, it reduces each key's versions down to the single one that was current
+// as of target and calls fn once per key with that winner (see reduceAtTarget). A
+// delete-marker winner is skipped unless SetIncludeDeleted(true) was called.
+func (s *Scanner) ScanAt(bucketName, prefix string, target time.Time, fn func(o *S3Object) error) (*BucketStatistics, error) {
+	return s.scan(bucketName, prefix, nil, s.reduceAtTarget(nil, target), fn)
+}
+
+// ScanAtWithExclusions combines ScanAt's point-in-time reduction with ScanWithExclusions'
+// exclusionMatcher pruning.
+func (s *Scanner) ScanAtWithExclusions(bucketName, prefix string, exclusionMatcher *ExclusionMatcher, target time.Time, fn func(o *S3Object) error) (*BucketStatistics, error) {
+	return s.scan(bucketName, prefix, exclusionMatcher, s.reduceAtTarget(exclusionMatcher, target), fn)
+}
+
+// consumeObjects returns the per-folder object consumer Scan/ScanWithExclusions use: it
+// drops objects exclusionMatcher excludes and calls fn for every one that's left.
+func (s *Scanner) consumeObjects(exclusionMatcher *ExclusionMatcher) func(objCh <-chan *S3Object, fn func(o *S3Object) error) int {
+	return func(objCh <-chan *S3Object, fn func(o *S3Object) error) int {
+		i := 0
+		for obj := range objCh {
+			if exclusionMatcher != nil && exclusionMatcher.ShouldSkipObject(*obj.Key) {
+				continue
+			}
+
+			i++
+			if err := fn(obj); err != nil {
+				s.logger.Println("Error in object processing function:", err)
+			}
+		}
+		return i
+	}
+}
+
+func (s *Scanner) scan(bucketName, prefix string, exclusionMatcher *ExclusionMatcher, consume func(objCh <-chan *S3Object, fn func(o *S3Object) error) int, fn func(o *S3Object) error) (*BucketStatistics, error) {
 
 	// check if the bucket is versioned
 	res, err := s.client.GetBucketVersioning(s.ctx, &s3.GetBucketVersioningInput{
@@ -148,7 +360,21 @@ func (s *Scanner) Scan(bucketName, prefix string, fn func(o *S3Object) error) (*
 
 	var wg sync.WaitGroup
 	stats := new(BucketStatistics)
-	b := newBucket(bucketName, prefix)
+
+	var checkpoints map[string]FolderCheckpoint
+	if s.checkpoints != nil {
+		checkpoints, err = s.checkpoints.LoadCheckpoints(bucketName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load checkpoints for %s: %v", bucketName, err)
+		}
+	}
+
+	var b *bucket
+	if exclusionMatcher != nil {
+		b = newBucketWithExclusions(bucketName, prefix, exclusionMatcher, checkpoints, s.scanOptions)
+	} else {
+		b = newBucket(bucketName, prefix, checkpoints, s.scanOptions)
+	}
 
 	for folder := range b.folders {
 		s.acquireWorker()
@@ -171,14 +397,7 @@ func (s *Scanner) Scan(bucketName, prefix string, fn func(o *S3Object) error) (*
 					}
 				}()
 
-				i := 0
-				for obj := range objCh {
-					i++
-					if err := fn(obj); err != nil {
-						s.logger.Println("Error in object processing function:", err)
-					}
-				}
-				stats.addObjects(i)
+				stats.addObjects(consume(objCh, fn))
 
 			}(objCh)
 
@@ -189,8 +408,11 @@ func (s *Scanner) Scan(bucketName, prefix string, fn func(o *S3Object) error) (*
 				s.logger.Printf("Failed to fetch prefix '%s': %v\n", folder.prefix, err)
 			}
 
-			// close the prefix channel
-			if b.isRoot(folder) {
+			// Close the folders channel once every root-level folder (the single
+			// root, or every shard when sharding is enabled) has finished listing;
+			// by then every CommonPrefix they could discover has already been
+			// queued.
+			if b.isRoot(folder) && b.finishRootFolder() {
 				b.closeFolders()
 			}
 
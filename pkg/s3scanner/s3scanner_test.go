@@ -1,8 +1,11 @@
 package s3scanner
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -48,6 +51,89 @@ func (m *mockS3Client) ListObjectVersions(ctx context.Context, params *s3.ListOb
 				},
 			},
 		}, nil
+	} else if *params.Prefix == "pitr/" {
+		pitrBaseTime := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+		return &s3.ListObjectVersionsOutput{
+			IsTruncated: aws.Bool(false),
+			// Both lists are pre-sorted by key then LastModified descending, the way S3
+			// actually returns them, to exercise fetchFolder's merge.
+			DeleteMarkers: []types.DeleteMarkerEntry{
+				{
+					Key:          aws.String("pitr/deleted"),
+					VersionId:    aws.String("d1"),
+					LastModified: aws.Time(pitrBaseTime.Add(30 * time.Minute)),
+					IsLatest:     aws.Bool(true),
+				},
+			},
+			Versions: []types.ObjectVersion{
+				{
+					Key:          aws.String("pitr/deleted"),
+					VersionId:    aws.String("v0"),
+					LastModified: aws.Time(pitrBaseTime),
+					IsLatest:     aws.Bool(false),
+				},
+				{
+					Key:          aws.String("pitr/object"),
+					VersionId:    aws.String("v3"),
+					LastModified: aws.Time(pitrBaseTime.Add(2 * time.Hour)),
+					IsLatest:     aws.Bool(true),
+				},
+				{
+					Key:          aws.String("pitr/object"),
+					VersionId:    aws.String("v2"),
+					LastModified: aws.Time(pitrBaseTime.Add(1 * time.Hour)),
+					IsLatest:     aws.Bool(false),
+				},
+				{
+					Key:          aws.String("pitr/object"),
+					VersionId:    aws.String("v1"),
+					LastModified: aws.Time(pitrBaseTime),
+					IsLatest:     aws.Bool(false),
+				},
+			},
+		}, nil
+	} else if *params.Prefix == "resume/" {
+		if params.KeyMarker == nil {
+			return &s3.ListObjectVersionsOutput{
+				IsTruncated:   aws.Bool(true),
+				NextKeyMarker: aws.String("k1"),
+				Versions: []types.ObjectVersion{
+					{
+						Key:          aws.String("resume/a"),
+						VersionId:    aws.String("v1"),
+						LastModified: aws.Time(time.Now()),
+						IsLatest:     aws.Bool(true),
+					},
+				},
+			}, nil
+		}
+		return &s3.ListObjectVersionsOutput{
+			IsTruncated: aws.Bool(false),
+			Versions: []types.ObjectVersion{
+				{
+					Key:          aws.String("resume/b"),
+					VersionId:    aws.String("v1"),
+					LastModified: aws.Time(time.Now()),
+					IsLatest:     aws.Bool(true),
+				},
+			},
+		}, nil
+	} else if *params.Prefix == "shard/0" {
+		return &s3.ListObjectVersionsOutput{
+			IsTruncated: aws.Bool(false),
+			Versions: []types.ObjectVersion{
+				{
+					Key:          aws.String("shard/0-object"),
+					VersionId:    aws.String("v1"),
+					LastModified: aws.Time(time.Now()),
+					IsLatest:     aws.Bool(true),
+				},
+			},
+		}, nil
+	} else if *params.Prefix == "shard/1" {
+		// Empty shard: exercises fetchFolder's guard against recursing or paginating
+		// further into a shard token with nothing in its keyspace.
+		return &s3.ListObjectVersionsOutput{IsTruncated: aws.Bool(false)}, nil
 	}
 	return nil, nil
 }
@@ -110,6 +196,139 @@ func TestScan(t *testing.T) {
 	}
 }
 
+func TestScanAt(t *testing.T) {
+	ctx := context.Background()
+	baseTime := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	target := baseTime.Add(1 * time.Hour)
+
+	mockClient := new(mockS3Client)
+	scanner, _ := NewScanner(mockClient, ctx, 2)
+
+	var got []*S3Object
+	stats, err := scanner.ScanAt("test-bucket", "pitr/", target, func(o *S3Object) error {
+		got = append(got, o)
+		return nil
+	})
+	if err != nil || stats == nil {
+		t.Fatalf("ScanAt failed: expected no error and stats, got error %v and stats %v", err, stats)
+	}
+
+	// "pitr/deleted"'s winner as of target is a delete marker, so by default it's
+	// dropped; "pitr/object"'s winner is v2, the newest version not after target.
+	if len(got) != 1 || *got[0].Key != "pitr/object" || *got[0].Metadata.VersionId != "v2" {
+		t.Errorf("ScanAt: expected a single winner pitr/object@v2, got %+v", got)
+	}
+
+	got = nil
+	scanner.SetIncludeDeleted(true)
+	_, err = scanner.ScanAt("test-bucket", "pitr/", target, func(o *S3Object) error {
+		got = append(got, o)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ScanAt with IncludeDeleted failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("ScanAt with IncludeDeleted: expected 2 winners, got %d (%+v)", len(got), got)
+	}
+}
+
+func TestScanWithSharding(t *testing.T) {
+	ctx := context.Background()
+
+	mockClient := new(mockS3Client)
+	scanner, _ := NewScanner(mockClient, ctx, 2)
+	scanner.SetScanOptions(ScanOptions{PrefixShardAlphabet: "01", PrefixShardLength: 1})
+
+	var got []*S3Object
+	stats, err := scanner.Scan("test-bucket", "shard/", func(o *S3Object) error {
+		got = append(got, o)
+		return nil
+	})
+
+	// Only shard "0" has anything in its keyspace; shard "1" comes back empty and
+	// shouldn't contribute an object or hang the scan.
+	if err != nil || stats == nil || stats.Objects != 1 {
+		t.Errorf("Scan with sharding failed: expected no error and 1 object, got error %v and stats %v", err, stats)
+	}
+	if len(got) != 1 || *got[0].Key != "shard/0-object" {
+		t.Errorf("Scan with sharding: expected shard/0-object, got %+v", got)
+	}
+}
+
+func TestScanResumable(t *testing.T) {
+	ctx := context.Background()
+	mockClient := new(mockS3Client)
+	scanner, _ := NewScanner(mockClient, ctx, 2)
+
+	var got []*S3Object
+	checkpoints, stats, err := scanner.ScanResumable("test-bucket", "resume/", nil, func(o *S3Object) error {
+		got = append(got, o)
+		return nil
+	})
+	if err != nil || stats == nil || stats.Objects != 2 {
+		t.Fatalf("ScanResumable: expected no error and 2 objects, got error %v and stats %v", err, stats)
+	}
+	if progress, ok := checkpoints["resume/"]; !ok || !progress.Done {
+		t.Errorf("ScanResumable: expected resume/ folder marked Done in the returned checkpoints, got %+v", checkpoints["resume/"])
+	}
+
+	// Resuming from a checkpoint that already consumed the first page should only
+	// refetch the second, not relist from scratch.
+	got = nil
+	resumeFrom := map[string]FolderCheckpoint{
+		"resume/": {KeyMarker: aws.String("k1")},
+	}
+	_, stats, err = scanner.ScanResumable("test-bucket", "resume/", resumeFrom, func(o *S3Object) error {
+		got = append(got, o)
+		return nil
+	})
+	if err != nil || stats == nil || stats.Objects != 1 {
+		t.Fatalf("ScanResumable resume: expected no error and 1 object, got error %v and stats %v", err, stats)
+	}
+	if len(got) != 1 || *got[0].Key != "resume/b" {
+		t.Errorf("ScanResumable resume: expected only resume/b, got %+v", got)
+	}
+
+	// SetCheckpointStore's prior state (nil store) must be restored afterward, so a
+	// plain Scan call right after ScanResumable doesn't keep checkpointing.
+	if scanner.checkpoints != nil {
+		t.Errorf("ScanResumable: expected the scanner's checkpoint store to be restored to nil after the call")
+	}
+}
+
+func TestScanAllVersions(t *testing.T) {
+	ctx := context.Background()
+
+	mockClient := new(mockS3Client)
+	scanner, _ := NewScanner(mockClient, ctx, 2)
+
+	var buf bytes.Buffer
+	var got []*S3Object
+	stats, err := scanner.ScanAllVersionsWithManifest("test-bucket", "pitr/", &buf, func(o *S3Object) error {
+		got = append(got, o)
+		return nil
+	})
+	if err != nil || stats == nil || stats.Objects != 5 {
+		t.Fatalf("ScanAllVersions: expected no error and 5 objects (every version and delete marker), got error %v and stats %v", err, stats)
+	}
+
+	for _, obj := range got {
+		if !strings.Contains(*obj.Key, "-v") || !strings.HasSuffix(*obj.Key, *obj.Metadata.VersionId) {
+			t.Errorf("ScanAllVersions: expected key rewritten with a -v.../VersionId suffix, got %s", *obj.Key)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 {
+		t.Errorf("ScanAllVersions: expected 5 manifest lines, got %d", len(lines))
+	}
+	var manifestEntry map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(lines[0]), &manifestEntry); err != nil || len(manifestEntry) != 1 {
+		t.Errorf("ScanAllVersions: expected each manifest line to be a single-key JSON object, got %q (err %v)", lines[0], err)
+	}
+}
+
 func TestVersionIdTiebreaker(t *testing.T) {
 	// Test the VersionId tiebreaker logic for objects with same LastModified time
 	// and neither marked as IsLatest
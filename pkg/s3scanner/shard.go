@@ -0,0 +1,36 @@
+package s3scanner
+
+// ScanOptions configures optional Scanner-wide behavior that doesn't fit a single Scan
+// call's argument list. See Scanner.SetScanOptions.
+type ScanOptions struct {
+	// PrefixShardAlphabet and PrefixShardLength, when PrefixShardLength > 0, shard a
+	// scan's prefix into every PrefixShardLength-long token the cartesian product of
+	// PrefixShardAlphabet produces (e.g. "0"-"f" at length 1, "00"-"ff" at length 2),
+	// appended to the prefix, and scan each shard as an independent root folder
+	// instead of starting from a single one. This works around S3's per-prefix
+	// request-rate ceiling the way Arvados keepstore's PrefixLength config shards
+	// object names into hex subprefixes.
+	PrefixShardAlphabet string
+	PrefixShardLength   int
+}
+
+// shardTokens returns every PrefixShardLength-long token the cartesian product of
+// PrefixShardAlphabet produces, or nil if sharding isn't enabled.
+func (o ScanOptions) shardTokens() []string {
+	if o.PrefixShardLength <= 0 || o.PrefixShardAlphabet == "" {
+		return nil
+	}
+
+	tokens := []string{""}
+	for i := 0; i < o.PrefixShardLength; i++ {
+		next := make([]string, 0, len(tokens)*len(o.PrefixShardAlphabet))
+		for _, t := range tokens {
+			for _, c := range o.PrefixShardAlphabet {
+				next = append(next, t+string(c))
+			}
+		}
+		tokens = next
+	}
+
+	return tokens
+}
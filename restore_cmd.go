@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/eliran89c/s3pitr/internal/csvutils"
+	"github.com/eliran89c/s3pitr/internal/restore"
+
+	"github.com/briandowns/spinner"
+	badger "github.com/dgraph-io/badger/v3"
+)
+
+// thawDBName is the default path of the local BadgerDB used to track in-flight Glacier/Deep
+// Archive restores across `restore` runs, the same way localDBName tracks scan progress.
+const thawDBName = ".s3pitr-thaw"
+
+// runRestore implements the `restore` subcommand: it reads the object/version list
+// produced by the default report CSV and performs the version-copy-in-place operation
+// (or a delete-marker replay) for each row, bounded by --maxConcurrentRestores, writing
+// a per-object success/failure CSV so partial runs are resumable. A row whose version is
+// still in GLACIER/DEEP_ARCHIVE is restored and waited on before the copy is retried; a
+// local BadgerDB records which versions are thawing so a restart doesn't reissue the
+// restore and reset a Bulk-tier wait that can take up to 12 hours.
+func runRestore(args []string) error {
+	var inputPath, resultName, restoreTierInput, thawDBPath string
+	var maxConcurrentRestores, restoreDays int
+
+	flagsSet := flag.NewFlagSet("restore", flag.ExitOnError)
+	flagsSet.StringVar(&inputPath, "input", "", "Path to a report CSV produced by s3pitr (required)")
+	flagsSet.StringVar(&resultName, "reportName", "restore-result.csv", "Name of the per-object restore result CSV")
+	flagsSet.IntVar(&maxConcurrentRestores, "maxConcurrentRestores", 50, "Maximum number of concurrent object restores")
+	flagsSet.StringVar(&restoreTierInput, "restore-tier", "Standard", "Glacier restore tier for still-archived versions: Bulk, Standard, or Expedited")
+	flagsSet.IntVar(&restoreDays, "restore-days", 1, "Number of days a restored archived version stays restored")
+	flagsSet.StringVar(&thawDBPath, "thaw-db-path", thawDBName, "Path to the local BadgerDB used to track in-flight archive restores across runs")
+	flagsSet.StringVar(&profile, "profile", "", "AWS profile to use for credentials")
+	flagsSet.StringVar(&region, "region", "", "AWS region to use")
+	flagsSet.StringVar(&roleArn, "role-arn", "", "AWS IAM role ARN to assume")
+
+	if err := flagsSet.Parse(args); err != nil {
+		return err
+	}
+
+	if inputPath == "" {
+		return fmt.Errorf("-input flag is required")
+	}
+
+	var restoreTier types.Tier
+	switch types.Tier(restoreTierInput) {
+	case types.TierBulk, types.TierStandard, types.TierExpedited:
+		restoreTier = types.Tier(restoreTierInput)
+	default:
+		return fmt.Errorf("unknown --restore-tier %q", restoreTierInput)
+	}
+
+	inputFile, err := os.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to open input CSV: %v", err)
+	}
+	defer inputFile.Close()
+
+	resultFile, err := os.Create(resultName)
+	if err != nil {
+		return fmt.Errorf("failed to create restore result CSV: %v", err)
+	}
+	defer resultFile.Close()
+
+	resultWriter := csv.NewWriter(resultFile)
+	defer resultWriter.Flush()
+
+	thawOpts := badger.DefaultOptions(thawDBPath)
+	thawOpts.Logger = nil // disable badger logger
+	thawDB, err := badger.Open(thawOpts)
+	if err != nil {
+		return fmt.Errorf("failed to open thaw-state DB: %v", err)
+	}
+	defer thawDB.Close()
+
+	ctx := context.Background()
+
+	cfg, err := getClientConfig(ctx)
+	if err != nil {
+		return err
+	}
+	client := s3.NewFromConfig(cfg)
+
+	executor, err := restore.NewExecutor(client, ctx, maxConcurrentRestores)
+	if err != nil {
+		return err
+	}
+	executor.SetArchiveRestore(restoreTier, int32(restoreDays))
+	executor.SetThawStore(&csvutils.BadgerThawStore{DB: thawDB})
+
+	sp := spinner.New(spinner.CharSets[32], 100*time.Millisecond)
+	sp.Prefix = fmt.Sprintf("Restoring objects from %s ", inputPath)
+	sp.Start()
+	defer sp.Stop()
+
+	recordsCh := make(chan restore.Record)
+	go func() {
+		defer close(recordsCh)
+
+		reader := csv.NewReader(inputFile)
+		reader.FieldsPerRecord = -1
+
+		for {
+			row, err := reader.Read()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				log.Println("Error reading input CSV row:", err)
+				return
+			}
+			if len(row) < 3 {
+				continue
+			}
+
+			key, err := url.PathUnescape(row[1])
+			if err != nil {
+				key = row[1]
+			}
+
+			record := restore.Record{Bucket: row[0], Key: key, VersionId: row[2]}
+			if len(row) > 3 {
+				record.IsDeleteMarker, _ = strconv.ParseBool(row[3])
+			}
+
+			recordsCh <- record
+		}
+	}()
+
+	var succeeded, failed int
+	for result := range executor.Restore(recordsCh) {
+		status := "success"
+		errMsg := ""
+		if result.Err != nil {
+			status = "failure"
+			errMsg = result.Err.Error()
+			failed++
+		} else {
+			succeeded++
+		}
+
+		if err := resultWriter.Write([]string{result.Bucket, result.Key, result.VersionId, status, errMsg}); err != nil {
+			log.Println("Error writing restore result row:", err)
+		}
+	}
+
+	sp.Stop()
+	fmt.Println("---Restore Results---")
+	fmt.Println("Succeeded:", succeeded)
+	fmt.Println("Failed:", failed)
+
+	return nil
+}